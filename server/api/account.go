@@ -0,0 +1,126 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type verifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+func (a ginHandler) VerifyEmail(c *gin.Context) {
+	var req verifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+	if err := a.accountService.VerifyEmail(c.Request.Context(), req.Token); err != nil {
+		renderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "email verified",
+	})
+}
+
+func (a ginHandler) ResendVerification(c *gin.Context) {
+	author := currentAuthor(c)
+	if err := a.accountService.SendVerification(c.Request.Context(), author); err != nil {
+		renderError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "verification email sent",
+	})
+}
+
+func (a ginHandler) ForgotPassword(c *gin.Context) {
+	var req forgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+	if err := a.accountService.RequestPasswordReset(c.Request.Context(), req.Email); err != nil {
+		renderError(c, err)
+		return
+	}
+	// The same response is sent whether or not the email has an account.
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "if the email has an account, a reset link has been sent",
+	})
+}
+
+func (a ginHandler) ResetPassword(c *gin.Context) {
+	var req resetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+	if err := a.accountService.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		renderError(c, err)
+		return
+	}
+	clearTokens(c)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "password reset, please log in again",
+	})
+}
+
+type totpCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+func (a ginHandler) EnrollTOTP(c *gin.Context) {
+	enrollment, err := a.accountService.EnrollTOTP(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"totp": enrollment,
+	})
+}
+
+func (a ginHandler) ConfirmTOTP(c *gin.Context) {
+	var req totpCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+	codes, err := a.accountService.ConfirmTOTP(c.Request.Context(), c.Param("id"), req.Code)
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+func (a ginHandler) DisableTOTP(c *gin.Context) {
+	var req totpCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+	err := a.accountService.DisableTOTP(c.Request.Context(), c.Param("id"), req.Code)
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "two-factor authentication disabled",
+	})
+}
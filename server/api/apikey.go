@@ -0,0 +1,58 @@
+package http
+
+import (
+	"net/http"
+
+	"example.com/server/app"
+	"github.com/gin-gonic/gin"
+)
+
+type apiKeyRequest struct {
+	Name   string `json:"name" binding:"required"`
+	Scopes string `json:"scopes" binding:"required"`
+}
+
+func (a ginHandler) GetAPIKeys(c *gin.Context) {
+	keys, err := a.keyService.ReadAPIKeys(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"keys": keys,
+	})
+}
+
+func (a ginHandler) PostAPIKey(c *gin.Context) {
+	var req apiKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+	scopes, err := app.ParseScopes(req.Scopes)
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	key, secret, err := a.keyService.CreateAPIKey(c.Request.Context(), c.Param("id"), req.Name, scopes)
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	// The full key is only ever shown in this response.
+	c.JSON(http.StatusCreated, gin.H{
+		"key":    key,
+		"secret": secret,
+	})
+}
+
+func (a ginHandler) DeleteAPIKey(c *gin.Context) {
+	err := a.keyService.RevokeAPIKey(c.Request.Context(), c.Param("id"), c.Param("key"))
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "api key revoked",
+	})
+}
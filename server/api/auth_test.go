@@ -0,0 +1,313 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/server/app"
+	"example.com/server/mail"
+	"example.com/server/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+const testPassword = "Correct-horse-1"
+
+// testServer is the API over the memory backend with one verified author
+// signed in.
+type testServer struct {
+	router  *gin.Engine
+	handler *ginHandler
+	store   *repository.MemoryDB
+	author  *app.Author
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	store := repository.NewMemoryDB()
+	handler := &ginHandler{
+		appService:     app.NewItemService(store, store, store, store),
+		sessionService: app.NewSessionService(store),
+		accountService: app.NewAccountService(store, store, mail.NewLogMailer(io.Discard), []byte("test secret"), "http://modart.test"),
+		keyService:     app.NewKeyService(store),
+		loginThrottle:  app.NewLoginThrottle(store, app.DefaultAccountPolicy, app.DefaultIPPolicy),
+		secret:         []byte("test secret"),
+	}
+	author, err := handler.appService.CreateAuthor(context.Background(), &app.Author{
+		FirstName: "Ada",
+		LastName:  "Lovelace",
+		Email:     "ada@example.com",
+		Password:  testPassword,
+	})
+	if err != nil {
+		t.Fatalf("creating author: %v", err)
+	}
+	author.EmailVerified = true
+	if author, err = store.UpdateAuthor(author); err != nil {
+		t.Fatalf("verifying author: %v", err)
+	}
+	router, err := newRouter(handler, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testServer{
+		router:  router,
+		handler: handler,
+		store:   store,
+		author:  author,
+	}
+}
+
+// login starts a session for the author and returns its access token and ID.
+func (s *testServer) login(t *testing.T) (string, string) {
+	t.Helper()
+	session, _, err := s.handler.sessionService.StartSession(context.Background(), s.author, "test", "192.0.2.1")
+	if err != nil {
+		t.Fatalf("starting session: %v", err)
+	}
+	token, err := s.handler.signToken(session)
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	return token, session.Id
+}
+
+// do sends a request with the token as a bearer token, when there is one, and
+// header as extra headers.
+func (s *testServer) do(method, path, token string, body interface{}, header ...string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	res := httptest.NewRecorder()
+	s.router.ServeHTTP(res, req)
+	return res
+}
+
+// route is a request to one route of the authorized group.
+type route struct {
+	method, pattern, path string
+}
+
+// authorizedRoutes lists every route behind RequireAuth with its parameters
+// filled in; TestAuthorizedRoutesAreListed keeps it complete.
+func authorizedRoutes(userID string) []route {
+	user := "/users/" + userID
+	return []route{
+		{"POST", "/users/logout", "/users/logout"},
+		{"POST", "/users/verify/resend", "/users/verify/resend"},
+		{"GET", "/users/sessions", "/users/sessions"},
+		{"DELETE", "/users/sessions/:sid", "/users/sessions/some-session"},
+		{"PUT", "/users/:id", user},
+		{"POST", "/users/:id/totp", user + "/totp"},
+		{"POST", "/users/:id/totp/confirm", user + "/totp/confirm"},
+		{"DELETE", "/users/:id/totp", user + "/totp"},
+		{"GET", "/users/:id/keys", user + "/keys"},
+		{"POST", "/users/:id/keys", user + "/keys"},
+		{"DELETE", "/users/:id/keys/:key", user + "/keys/some-key"},
+		{"DELETE", "/users/:id", user},
+		{"GET", "/users/:id/drafts", user + "/drafts"},
+		{"POST", "/articles", "/articles"},
+		{"PUT", "/articles/:id", "/articles/some-article"},
+		{"DELETE", "/articles/:id", "/articles/some-article"},
+		{"POST", "/articles/:id/publish", "/articles/some-article/publish"},
+		{"POST", "/articles/:id/unpublish", "/articles/some-article/unpublish"},
+		{"POST", "/articles/:id/archive", "/articles/some-article/archive"},
+		{"GET", "/articles/:id/revisions", "/articles/some-article/revisions"},
+		{"GET", "/articles/:id/revisions/diff", "/articles/some-article/revisions/diff?from=1&to=2"},
+		{"GET", "/articles/:id/revisions/:rev", "/articles/some-article/revisions/1"},
+		{"POST", "/articles/:id/revisions/:rev/restore", "/articles/some-article/revisions/1/restore"},
+	}
+}
+
+// publicRoutes are served without a token.
+var publicRoutes = map[string]bool{
+	"GET /":                       true,
+	"POST /users/login":           true,
+	"POST /users/login/totp":      true,
+	"POST /users/signup":          true,
+	"POST /users/refresh":         true,
+	"POST /users/verify":          true,
+	"POST /users/password/forgot": true,
+	"POST /users/password/reset":  true,
+	"GET /users":                  true,
+	"GET /users/:id":              true,
+	"GET /articles":               true,
+	"GET /articles/search":        true,
+	"GET /articles/:id":           true,
+}
+
+func TestAuthorizedRoutesAreListed(t *testing.T) {
+	s := newTestServer(t)
+	listed := map[string]bool{}
+	for _, r := range authorizedRoutes(s.author.Id) {
+		listed[r.method+" "+r.pattern] = true
+	}
+	for _, info := range s.router.Routes() {
+		key := info.Method + " " + info.Path
+		if !publicRoutes[key] && !listed[key] {
+			t.Errorf("%s is neither public nor in authorizedRoutes", key)
+		}
+	}
+}
+
+func TestAuthorizedRoutesRejectMissingToken(t *testing.T) {
+	s := newTestServer(t)
+	for _, r := range authorizedRoutes(s.author.Id) {
+		if res := s.do(r.method, r.path, "", nil); res.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s without a token: got %d, want 401", r.method, r.path, res.Code)
+		}
+	}
+}
+
+func TestAuthorizedRoutesRejectBadToken(t *testing.T) {
+	s := newTestServer(t)
+	valid, sid := s.login(t)
+	sign := func(secret string, claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	revoked, _ := s.login(t)
+	ctx := app.NewContext(context.Background(), s.author)
+	_, revokedSID, _ := s.handler.parseToken(revoked)
+	if err := s.handler.sessionService.RevokeSession(ctx, revokedSID); err != nil {
+		t.Fatal(err)
+	}
+	challenge, err := s.handler.signChallenge(s.author.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := map[string]string{
+		"garbage":      "not-a-jwt",
+		"tampered":     valid[:len(valid)-2] + "xx",
+		"wrong secret": sign("other secret", jwt.MapClaims{"sub": s.author.Id, "sid": sid, "exp": time.Now().Add(time.Minute).Unix()}),
+		"expired":      sign("test secret", jwt.MapClaims{"sub": s.author.Id, "sid": sid, "exp": time.Now().Add(-time.Minute).Unix()}),
+		"no session":   sign("test secret", jwt.MapClaims{"sub": s.author.Id, "exp": time.Now().Add(time.Minute).Unix()}),
+		"other author": sign("test secret", jwt.MapClaims{"sub": "someone-else", "sid": sid, "exp": time.Now().Add(time.Minute).Unix()}),
+		"revoked":      revoked,
+		"challenge":    challenge,
+		"api key":      "mdk_unknown.secret",
+	}
+	for name, token := range tokens {
+		for _, r := range authorizedRoutes(s.author.Id) {
+			if res := s.do(r.method, r.path, token, nil); res.Code != http.StatusUnauthorized {
+				t.Errorf("%s %s with a %s token: got %d, want 401", r.method, r.path, name, res.Code)
+			}
+		}
+	}
+	res := s.do("GET", "/users/sessions", "", nil, "Authorization", "Basic "+valid)
+	if res.Code != http.StatusUnauthorized {
+		t.Errorf("non-bearer Authorization header: got %d, want 401", res.Code)
+	}
+}
+
+// TestAuthorizedRoutesAcceptValidToken walks every authorized route in an
+// order that gives each the state it needs to succeed.
+func TestAuthorizedRoutesAcceptValidToken(t *testing.T) {
+	s := newTestServer(t)
+	token, _ := s.login(t)
+	user := "/users/" + s.author.Id
+	covered := map[string]bool{}
+
+	call := func(r route, want int, body interface{}, header ...string) map[string]interface{} {
+		t.Helper()
+		covered[r.method+" "+r.pattern] = true
+		res := s.do(r.method, r.path, token, body, header...)
+		if res.Code != want {
+			t.Fatalf("%s %s: got %d, want %d: %s", r.method, r.path, res.Code, want, res.Body)
+		}
+		var decoded map[string]interface{}
+		json.Unmarshal(res.Body.Bytes(), &decoded)
+		return decoded
+	}
+
+	call(route{"POST", "/users/verify/resend", "/users/verify/resend"}, http.StatusAccepted, nil)
+	call(route{"GET", "/users/sessions", "/users/sessions"}, http.StatusOK, nil)
+	call(route{"PUT", "/users/:id", user}, http.StatusOK, map[string]string{"firstname": "Augusta"},
+		"If-Match", fmt.Sprintf(`"%d"`, s.author.Version))
+
+	enrolled := call(route{"POST", "/users/:id/totp", user + "/totp"}, http.StatusCreated, nil)
+	secret := enrolled["totp"].(map[string]interface{})["secret"].(string)
+	confirmed := call(route{"POST", "/users/:id/totp/confirm", user + "/totp/confirm"}, http.StatusOK,
+		map[string]string{"code": totpCode(t, secret, time.Now())})
+	recovery := confirmed["recovery_codes"].([]interface{})[0].(string)
+	call(route{"DELETE", "/users/:id/totp", user + "/totp"}, http.StatusOK, map[string]string{"code": recovery})
+
+	call(route{"GET", "/users/:id/keys", user + "/keys"}, http.StatusOK, nil)
+	created := call(route{"POST", "/users/:id/keys", user + "/keys"}, http.StatusCreated,
+		map[string]string{"name": "ci", "scopes": "read"})
+	keyID := created["key"].(map[string]interface{})["id"].(string)
+	call(route{"DELETE", "/users/:id/keys/:key", user + "/keys/" + keyID}, http.StatusOK, nil)
+
+	posted := call(route{"POST", "/articles", "/articles"}, http.StatusCreated,
+		map[string]interface{}{"title": "Notes", "body": "On the analytical engine.", "rate": 3})
+	article := "/articles/" + posted["article"].(map[string]interface{})["id"].(string)
+	call(route{"GET", "/users/:id/drafts", user + "/drafts"}, http.StatusOK, nil)
+	call(route{"PUT", "/articles/:id", article}, http.StatusOK, map[string]string{"title": "Sketch"}, "If-Match", `"1"`)
+	call(route{"GET", "/articles/:id/revisions", article + "/revisions"}, http.StatusOK, nil)
+	call(route{"GET", "/articles/:id/revisions/diff", article + "/revisions/diff?from=1&to=2"}, http.StatusOK, nil)
+	call(route{"GET", "/articles/:id/revisions/:rev", article + "/revisions/1"}, http.StatusOK, nil)
+	call(route{"POST", "/articles/:id/revisions/:rev/restore", article + "/revisions/1/restore"}, http.StatusOK, nil)
+	call(route{"POST", "/articles/:id/publish", article + "/publish"}, http.StatusOK, nil)
+	call(route{"POST", "/articles/:id/unpublish", article + "/unpublish"}, http.StatusOK, nil)
+	call(route{"POST", "/articles/:id/archive", article + "/archive"}, http.StatusOK, nil)
+	call(route{"DELETE", "/articles/:id", article}, http.StatusNoContent, nil)
+
+	_, other := s.login(t)
+	call(route{"DELETE", "/users/sessions/:sid", "/users/sessions/" + other}, http.StatusOK, nil)
+	current := token
+	token, _ = s.login(t)
+	call(route{"POST", "/users/logout", "/users/logout"}, http.StatusOK, nil)
+	token = current
+	call(route{"DELETE", "/users/:id", user}, http.StatusNoContent, nil)
+
+	for _, r := range authorizedRoutes(s.author.Id) {
+		if !covered[r.method+" "+r.pattern] {
+			t.Errorf("%s %s was not exercised with a valid token", r.method, r.pattern)
+		}
+	}
+}
+
+// totpCode computes the RFC 6238 code an authenticator app would show for
+// the base32 secret at now.
+func totpCode(t *testing.T, secret string, now time.Time) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		t.Fatalf("decoding TOTP secret: %v", err)
+	}
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(now.Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
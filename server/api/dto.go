@@ -0,0 +1,60 @@
+package http
+
+import "example.com/server/app"
+
+// authorRequest is the body accepted when creating or updating an author.
+// CurrentPassword is only read by updates that change the password.
+type authorRequest struct {
+	FirstName       string   `json:"firstname"`
+	LastName        string   `json:"lastname"`
+	Email           string   `json:"email"`
+	Password        string   `json:"password"`
+	CurrentPassword string   `json:"current_password"`
+	Role            app.Role `json:"role"`
+}
+
+func (r authorRequest) toAuthor() *app.Author {
+	return &app.Author{
+		FirstName: r.FirstName,
+		LastName:  r.LastName,
+		Email:     r.Email,
+		Password:  r.Password,
+		Role:      r.Role,
+	}
+}
+
+// authorResponse is what clients see of an author. It deliberately has no
+// password or secret fields.
+type authorResponse struct {
+	Id            string        `json:"id"`
+	FirstName     string        `json:"firstname"`
+	LastName      string        `json:"lastname"`
+	Email         string        `json:"email"`
+	Role          app.Role      `json:"role"`
+	EmailVerified bool          `json:"email_verified"`
+	TOTPEnabled   bool          `json:"totp_enabled"`
+	Articles      []app.Article `json:"articles"`
+	Version       int64         `json:"version"`
+}
+
+func newAuthorResponse(author *app.Author) authorResponse {
+	return authorResponse{
+		Id:            author.Id,
+		FirstName:     author.FirstName,
+		LastName:      author.LastName,
+		Email:         author.Email,
+		Role:          author.EffectiveRole(),
+		EmailVerified: author.EmailVerified,
+		TOTPEnabled:   author.TOTPEnabled,
+		Articles:      author.Articles,
+		Version:       author.Version,
+	}
+}
+
+func newAuthorResponses(authors []*app.Author) []authorResponse {
+	res := make([]authorResponse, len(authors))
+	for i, author := range authors {
+		res[i] = newAuthorResponse(author)
+	}
+	return res
+}
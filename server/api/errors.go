@@ -0,0 +1,97 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"example.com/server/app"
+	"github.com/gin-gonic/gin"
+)
+
+// problemContentType is the media type of RFC 7807 error responses.
+const problemContentType = "application/problem+json"
+
+// problem is the RFC 7807 body sent for every failed request. Errors lists
+// the offending fields when input was rejected.
+type problem struct {
+	Type     string           `json:"type"`
+	Title    string           `json:"title"`
+	Status   int              `json:"status"`
+	Detail   string           `json:"detail,omitempty"`
+	Instance string           `json:"instance,omitempty"`
+	Errors   []app.FieldError `json:"errors,omitempty"`
+}
+
+// statusFor maps a service error onto its HTTP status by the kind of error
+// it wraps. Anything unrecognised is an internal error.
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, app.ErrVersionMismatch):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, app.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, app.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, app.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, app.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, app.ErrInvalid):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// renderError aborts the request with the problem for err. Internal errors
+// are logged rather than shown to the client.
+func renderError(c *gin.Context, err error) {
+	status := statusFor(err)
+	if status == http.StatusInternalServerError {
+		log.Printf("%s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+		renderProblem(c, status, "")
+		return
+	}
+	p := newProblem(c, status, err.Error())
+	var invalid *app.ValidationError
+	if errors.As(err, &invalid) {
+		p.Errors = invalid.Fields
+	}
+	writeProblem(c, p)
+}
+
+// renderProblem aborts the request with a problem that has no underlying
+// service error, such as a missing cookie or a throttled login.
+func renderProblem(c *gin.Context, status int, detail string) {
+	writeProblem(c, newProblem(c, status, detail))
+}
+
+// badRequest rejects a body or query that could not be bound. A JSON value of
+// the wrong type is reported against its field like a validation failure.
+func badRequest(c *gin.Context, err error) {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		renderError(c, app.NewValidationError(app.FieldError{
+			Field:   typeErr.Field,
+			Message: "must be of type " + typeErr.Type.String(),
+		}))
+		return
+	}
+	renderProblem(c, http.StatusBadRequest, err.Error())
+}
+
+func newProblem(c *gin.Context, status int, detail string) problem {
+	return problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: c.Request.URL.Path,
+	}
+}
+
+func writeProblem(c *gin.Context, p problem) {
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
@@ -0,0 +1,34 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setETag identifies the version of the item in the response, for clients to
+// send back in If-Match.
+func setETag(c *gin.Context, version int64) {
+	c.Header("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// ifMatch reads the version a conditional write expects from If-Match. It
+// aborts with 428 when the header is missing and 412 when it names no
+// version, as a wildcard or weak tag does, and reports whether to go on.
+func ifMatch(c *gin.Context) (int64, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		renderProblem(c, http.StatusPreconditionRequired, "If-Match with the ETag of the item being updated is required")
+		return 0, false
+	}
+	tag, err := strconv.Unquote(header)
+	if err == nil {
+		if version, err := strconv.ParseInt(tag, 10, 64); err == nil {
+			return version, true
+		}
+	}
+	renderProblem(c, http.StatusPreconditionFailed, "If-Match must be a single ETag from this API")
+	return 0, false
+}
@@ -1,18 +1,25 @@
 package http
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"os"
-	"time"
 
 	"example.com/server/app"
+	"example.com/server/config"
+	"example.com/server/mail"
+	"example.com/server/oidc"
 	"example.com/server/repository"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
 )
 
 type GinRoutehandler interface {
 	LoginUser(*gin.Context)
+	LoginUserTOTP(*gin.Context)
+	LoginOIDC(*gin.Context)
+	LoginOIDCCallback(*gin.Context)
 	GetUser(*gin.Context)
 	GetUsers(*gin.Context)
 	PostUser(*gin.Context)
@@ -20,167 +27,303 @@ type GinRoutehandler interface {
 	DeleteUser(*gin.Context)
 	GetArticle(*gin.Context)
 	GetArticles(*gin.Context)
+	SearchArticles(*gin.Context)
 	PostArticle(*gin.Context)
 	PutArticle(*gin.Context)
 	DeleteArticle(*gin.Context)
+	PublishArticle(*gin.Context)
+	UnpublishArticle(*gin.Context)
+	ArchiveArticle(*gin.Context)
+	GetDrafts(*gin.Context)
+	GetRevisions(*gin.Context)
+	GetRevision(*gin.Context)
+	DiffRevisions(*gin.Context)
+	RestoreRevision(*gin.Context)
+	RefreshSession(*gin.Context)
+	LogoutUser(*gin.Context)
+	GetSessions(*gin.Context)
+	DeleteSession(*gin.Context)
+	VerifyEmail(*gin.Context)
+	ResendVerification(*gin.Context)
+	ForgotPassword(*gin.Context)
+	ResetPassword(*gin.Context)
+	EnrollTOTP(*gin.Context)
+	ConfirmTOTP(*gin.Context)
+	DisableTOTP(*gin.Context)
+	GetAPIKeys(*gin.Context)
+	PostAPIKey(*gin.Context)
+	DeleteAPIKey(*gin.Context)
+	RequireAuth(*gin.Context)
+	OptionalAuth(*gin.Context)
 }
 
 type ginHandler struct {
-	appService app.AppService
+	appService     app.AppService
+	sessionService app.SessionService
+	accountService app.AccountService
+	keyService     app.KeyService
+	oidcProvider   *oidc.Provider
+	loginThrottle  app.LoginThrottle
+	// secret signs the tokens the handlers issue.
+	secret []byte
 }
 
-func NewHandler(appSrv app.AppService) GinRoutehandler {
+// NewHandler builds the route handlers. oidcProvider may be nil when social
+// login is not configured.
+func NewHandler(appSrv app.AppService, sessionSrv app.SessionService, accountSrv app.AccountService, keySrv app.KeyService, oidcProvider *oidc.Provider, loginThrottle app.LoginThrottle, secret []byte) GinRoutehandler {
 	return &ginHandler{
 		appSrv,
+		sessionSrv,
+		accountSrv,
+		keySrv,
+		oidcProvider,
+		loginThrottle,
+		secret,
 	}
 }
 
 // Author handler
 func (a ginHandler) GetUsers(c *gin.Context) {
-	users, err := a.appService.ReadAuthors()
+	page, err := pageRequest(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"err": err.Error(),
-		})
+		renderError(c, err)
+		return
+	}
+	users, err := a.appService.ReadAuthors(c.Request.Context(), page)
+	if err != nil {
+		renderError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
-		"users": users,
+		"users":       newAuthorResponses(users.Authors),
+		"next_cursor": users.NextCursor,
 	})
 }
 
 func (a ginHandler) GetUser(c *gin.Context) {
 	id := c.Param("id")
-	user, err := a.appService.ReadAuthor(id)
+	user, err := a.appService.ReadAuthor(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"err": err.Error(),
-		})
+		renderError(c, err)
 		return
 	}
 	if user == nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"message": "user not found",
-		})
+		renderProblem(c, http.StatusNotFound, "user not found")
 		return
 	}
+	setETag(c, user.Version)
 	c.JSON(http.StatusOK, gin.H{
-		"user": user,
+		"user": newAuthorResponse(user),
 	})
 	return
 }
 
+type loginRequest struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
 func (a ginHandler) LoginUser(c *gin.Context) {
-	email := c.Param("email")
-	author, err := a.appService.ReadAuthor(email)
+	var req loginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+	if !a.allowLogin(c, req.Email) {
+		return
+	}
+	author, err := a.appService.LoginAuthor(c.Request.Context(), req.Email, req.Password)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"err": err.Error(),
+		if errors.Is(err, app.ErrUnknownAuthor) || errors.Is(err, app.ErrWrongPassword) {
+			reason := "wrong_password"
+			if errors.Is(err, app.ErrUnknownAuthor) {
+				reason = "unknown_author"
+			}
+			a.loginFailed(c, req.Email, reason)
+			// Both cases share one response so callers cannot probe for emails.
+			renderProblem(c, http.StatusUnauthorized, "invalid email or password")
+			return
+		}
+		renderError(c, err)
+		return
+	}
+	a.loginSucceeded(c, req.Email)
+	a.completeLogin(c, author)
+}
+
+// completeLogin starts a session for an author whose password or external
+// identity checked out, or asks for the TOTP step when it is enabled.
+func (a ginHandler) completeLogin(c *gin.Context, author *app.Author) {
+	if author.TOTPEnabled {
+		challenge, err := a.signChallenge(author.Id)
+		if err != nil {
+			renderError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "two-factor code required",
+			"mfa_required": true,
+			"challenge":    challenge,
 		})
 		return
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": author.Email,
-		"exp": time.Now().Add(time.Minute * 60).Unix(),
-	})
-	// Sign and get the complete encoded token as a string using the secret
-	tokenString, err := token.SignedString([]byte(os.Getenv("SECRET")))
+	a.startSession(c, author)
+}
+
+type loginTOTPRequest struct {
+	Challenge string `json:"challenge" binding:"required"`
+	Code      string `json:"code" binding:"required"`
+}
 
+// LoginUserTOTP completes a two-step login by exchanging the challenge from
+// LoginUser and a TOTP or recovery code for a session.
+func (a ginHandler) LoginUserTOTP(c *gin.Context) {
+	var req loginTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+	id, err := a.parseChallenge(req.Challenge)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		renderProblem(c, http.StatusUnauthorized, "invalid or expired challenge")
 		return
 	}
-	c.SetSameSite(http.SameSiteLaxMode)
-	c.SetCookie("Authorization", tokenString, 3600*24*30, "", "", false, true)
-	c.JSON(http.StatusOK, gin.H{
-		"message": "login successful",
-	})
+	if !a.allowLogin(c, id) {
+		return
+	}
+	author, err := a.accountService.VerifySecondFactor(c.Request.Context(), id, req.Code)
+	if err != nil {
+		if errors.Is(err, app.ErrInvalidCode) {
+			a.loginFailed(c, id, "invalid_code")
+			// A wrong code fails the login rather than the request body.
+			renderProblem(c, http.StatusUnauthorized, "invalid authentication code")
+			return
+		}
+		renderError(c, err)
+		return
+	}
+	a.loginSucceeded(c, id)
+	a.startSession(c, author)
+}
+
+func (a ginHandler) startSession(c *gin.Context, author *app.Author) {
+	session, refreshToken, err := a.sessionService.StartSession(c.Request.Context(), author, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	a.issueTokens(c, session, refreshToken, "login successful")
 }
 
 func (a ginHandler) PostUser(c *gin.Context) {
-	var user app.Author
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+	var req authorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
 		return
 	}
-	res, err := a.appService.CreateAuthor(&user)
+	res, err := a.appService.CreateAuthor(c.Request.Context(), req.toAuthor())
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		renderError(c, err)
 		return
 	}
-
+	if err := a.accountService.SendVerification(c.Request.Context(), res); err != nil {
+		log.Printf("sending verification email to %s: %v", res.Email, err)
+	}
+	setETag(c, res.Version)
 	c.JSON(http.StatusCreated, gin.H{
-		"user": res,
+		"user": newAuthorResponse(res),
 	})
 	return
 }
 
 func (a ginHandler) PutUser(c *gin.Context) {
-	var user app.Author
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+	var req authorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+	version, ok := ifMatch(c)
+	if !ok {
 		return
 	}
-	res, err := a.appService.UpdateAuthor(&user)
+	user := req.toAuthor()
+	user.Id = c.Param("id")
+	user.Version = version
+	res, err := a.appService.UpdateAuthor(c.Request.Context(), user, req.CurrentPassword)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		renderError(c, err)
 		return
 	}
+	setETag(c, res.Version)
 
-	c.JSON(http.StatusCreated, gin.H{
-		"user": res,
+	c.JSON(http.StatusOK, gin.H{
+		"user": newAuthorResponse(res),
 	})
 	return
 }
 
 func (a ginHandler) DeleteUser(c *gin.Context) {
 	id := c.Param("id")
-	res := a.appService.DeleteAuthor(id)
-	c.JSON(http.StatusCreated, gin.H{
-		"user": res,
-	})
+	if err := a.appService.DeleteAuthor(c.Request.Context(), id); err != nil {
+		renderError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
 }
 
 // Article handler
 func (a ginHandler) GetArticles(c *gin.Context) {
-	articles, err := a.appService.ReadArticles()
+	page, err := pageRequest(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"err": err.Error(),
-		})
+		renderError(c, err)
+		return
+	}
+	query, err := articleQuery(c)
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	articles, err := a.appService.ReadArticles(c.Request.Context(), query, page)
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"articles":    articles.Articles,
+		"next_cursor": articles.NextCursor,
+	})
+}
+
+// SearchArticles runs a full-text search for ?q= over titles and bodies.
+func (a ginHandler) SearchArticles(c *gin.Context) {
+	page, err := pageRequest(c)
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	hits, err := a.appService.SearchArticles(c.Request.Context(), c.Query("q"), page.Limit)
+	if err != nil {
+		renderError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
-		"articles": articles,
+		"results": hits,
 	})
 }
 
 func (a ginHandler) GetArticle(c *gin.Context) {
 	id := c.Param("id")
-	article, err := a.appService.ReadArticle(id)
+	article, err := a.appService.ReadArticle(c.Request.Context(), id)
 
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"err": err.Error(),
-		})
+		renderError(c, err)
 		return
 	}
 	if article == nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"message": "article not found",
-		})
+		renderProblem(c, http.StatusNotFound, "article not found")
 		return
 	}
+	setETag(c, article.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"articles": article,
 	})
@@ -190,19 +333,16 @@ func (a ginHandler) GetArticle(c *gin.Context) {
 func (a ginHandler) PostArticle(c *gin.Context) {
 	var article app.Article
 	if err := c.ShouldBindJSON(&article); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		badRequest(c, err)
 		return
 	}
 
-	res, err := a.appService.CreateArticle(&article)
+	res, err := a.appService.CreateArticle(c.Request.Context(), &article)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		renderError(c, err)
 		return
 	}
+	setETag(c, res.Version)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"article": res,
@@ -211,21 +351,24 @@ func (a ginHandler) PostArticle(c *gin.Context) {
 }
 
 func (a ginHandler) PutArticle(c *gin.Context) {
-	var article app.Article
-	if err := c.ShouldBindJSON(&article); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+	var update app.ArticleUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		badRequest(c, err)
 		return
 	}
-	res, err := a.appService.UpdateArticle(&article)
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	update.Id = c.Param("id")
+	update.Version = version
+	res, err := a.appService.UpdateArticle(c.Request.Context(), &update)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		renderError(c, err)
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{
+	setETag(c, res.Version)
+	c.JSON(http.StatusOK, gin.H{
 		"article": res,
 	})
 	return
@@ -233,28 +376,98 @@ func (a ginHandler) PutArticle(c *gin.Context) {
 
 func (a ginHandler) DeleteArticle(c *gin.Context) {
 	id := c.Param("id")
-	err := a.appService.DeleteArticle(id)
+	err := a.appService.DeleteArticle(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusCreated, gin.H{
-			"error": "article not deleted",
-		})
+		renderError(c, err)
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "article deleted successfully",
-	})
+	c.Status(http.StatusNoContent)
+}
+
+// newMailer builds the mailer cfg describes. A mail log that cannot be
+// opened falls back to stdout rather than stopping the server.
+func newMailer(cfg config.Mail) app.Mailer {
+	if cfg.SMTPHost != "" {
+		return mail.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From)
+	}
+	if cfg.LogFile != "" {
+		mailer, err := mail.NewFileMailer(cfg.LogFile)
+		if err == nil {
+			return mailer
+		}
+		log.Printf("opening mail log %s: %v", cfg.LogFile, err)
+	}
+	return mail.NewLogMailer(os.Stdout)
+}
+
+// newStore opens the backend cfg selects. The memory search index of the
+// backends without full-text search is built from the stored articles.
+func newStore(cfg config.Storage) (repository.Store, error) {
+	switch cfg.Backend {
+	case config.BackendDynamoDB:
+		db, err := repository.InitDynamoDB(cfg.DynamoDB)
+		if err != nil {
+			return nil, err
+		}
+		rebuildIndex(db)
+		return db, nil
+	case config.BackendPostgres:
+		return repository.NewPostgresqlDB(cfg.Postgres)
+	case config.BackendSQLite:
+		return repository.NewSQLiteDB(cfg.SQLite)
+	case config.BackendMemory:
+		return repository.NewMemoryDB(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
+
+// rebuildIndex fills store's memory search index. Search is only stale
+// without it, so a failure is logged rather than stopping the server.
+func rebuildIndex(store repository.Store) {
+	if err := app.RebuildIndex(store, store); err != nil {
+		log.Printf("building search index: %v", err)
+	}
+}
 
+// InitGinRoute builds the router and the scheduler that publishes scheduled
+// articles, which the caller runs alongside it, from cfg.
+func InitGinRoute(cfg *config.Config) (*gin.Engine, app.Scheduler, error) {
+	dbClient, err := newStore(cfg.Storage)
+	if err != nil {
+		return nil, nil, err
+	}
+	srv := app.NewItemService(dbClient, dbClient, dbClient, dbClient)
+	scheduler := app.NewScheduler(dbClient, dbClient, app.SystemClock, cfg.PublishInterval)
+	sessionSrv := app.NewSessionService(dbClient)
+	accountSrv := app.NewAccountService(dbClient, dbClient, newMailer(cfg.Mail), []byte(cfg.Secret), cfg.AppURL)
+	keySrv := app.NewKeyService(dbClient)
+
+	var oidcProvider *oidc.Provider
+	if cfg.OIDC.Issuer != "" {
+		oidcProvider = oidc.NewProvider(cfg.OIDC.Issuer, cfg.OIDC.ClientID, cfg.OIDC.ClientSecret, cfg.OIDC.RedirectURL)
+	}
+
+	loginThrottle := app.NewLoginThrottle(dbClient, app.DefaultAccountPolicy, app.DefaultIPPolicy)
+
+	handler := NewHandler(srv, sessionSrv, accountSrv, keySrv, oidcProvider, loginThrottle, []byte(cfg.Secret))
+	router, err := newRouter(handler, oidcProvider != nil, cfg.TrustedProxies)
+	if err != nil {
+		return nil, nil, err
+	}
+	return router, scheduler, nil
 }
 
-func InitGinRoute() *gin.Engine {
+// newRouter routes requests to handler. The OpenID Connect login routes are
+// only added withOIDC. Client IPs come from X-Forwarded-For only when the
+// request is from one of trustedProxies.
+func newRouter(handler GinRoutehandler, withOIDC bool, trustedProxies []string) (*gin.Engine, error) {
 	// gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
 	// r.Use(cors.Default())
-
-	dbClient := repository.InitDynamoDB()
-	srv := app.NewItemService(dbClient)
-
-	handler := NewHandler(srv)
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, fmt.Errorf("setting trusted proxies: %v", err)
+	}
 
 	r.GET("/", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -263,17 +476,48 @@ func InitGinRoute() *gin.Engine {
 	})
 	// Authentication
 	r.POST("/users/login", handler.LoginUser)
+	r.POST("/users/login/totp", handler.LoginUserTOTP)
+	if withOIDC {
+		r.GET("/users/login/oidc", handler.LoginOIDC)
+		r.GET("/users/login/oidc/callback", handler.LoginOIDCCallback)
+	}
 	r.POST("/users/signup", handler.PostUser)
+	r.POST("/users/refresh", handler.RefreshSession)
+	r.POST("/users/verify", handler.VerifyEmail)
+	r.POST("/users/password/forgot", handler.ForgotPassword)
+	r.POST("/users/password/reset", handler.ResetPassword)
 	// Pull resources
 	r.GET("/users", handler.GetUsers)
 	r.GET("/users/:id", handler.GetUser)
-	r.PUT("/users/:id", handler.PutUser)
-	r.DELETE("/users/:id", handler.DeleteUser)
-	r.GET("/articles", handler.GetArticles)
-	r.GET("/articles/:id", handler.GetArticle)
-	r.POST("/articles", handler.PostArticle)
-	r.PUT("/articles/:id", handler.PutArticle)
-	r.DELETE("/articles/:id", handler.DeleteArticle)
-
-	return r
+	r.GET("/articles/search", handler.SearchArticles)
+	readable := r.Group("/", handler.OptionalAuth)
+	readable.GET("/articles", handler.GetArticles)
+	readable.GET("/articles/:id", handler.GetArticle)
+	// Mutate resources
+	authorized := r.Group("/", handler.RequireAuth)
+	authorized.POST("/users/logout", handler.LogoutUser)
+	authorized.POST("/users/verify/resend", handler.ResendVerification)
+	authorized.GET("/users/sessions", handler.GetSessions)
+	authorized.DELETE("/users/sessions/:sid", handler.DeleteSession)
+	authorized.PUT("/users/:id", handler.PutUser)
+	authorized.POST("/users/:id/totp", handler.EnrollTOTP)
+	authorized.POST("/users/:id/totp/confirm", handler.ConfirmTOTP)
+	authorized.DELETE("/users/:id/totp", handler.DisableTOTP)
+	authorized.GET("/users/:id/keys", handler.GetAPIKeys)
+	authorized.POST("/users/:id/keys", handler.PostAPIKey)
+	authorized.DELETE("/users/:id/keys/:key", handler.DeleteAPIKey)
+	authorized.DELETE("/users/:id", handler.DeleteUser)
+	authorized.GET("/users/:id/drafts", handler.GetDrafts)
+	authorized.POST("/articles", handler.PostArticle)
+	authorized.PUT("/articles/:id", handler.PutArticle)
+	authorized.DELETE("/articles/:id", handler.DeleteArticle)
+	authorized.POST("/articles/:id/publish", handler.PublishArticle)
+	authorized.POST("/articles/:id/unpublish", handler.UnpublishArticle)
+	authorized.POST("/articles/:id/archive", handler.ArchiveArticle)
+	authorized.GET("/articles/:id/revisions", handler.GetRevisions)
+	authorized.GET("/articles/:id/revisions/diff", handler.DiffRevisions)
+	authorized.GET("/articles/:id/revisions/:rev", handler.GetRevision)
+	authorized.POST("/articles/:id/revisions/:rev/restore", handler.RestoreRevision)
+
+	return r, nil
 }
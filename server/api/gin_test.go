@@ -0,0 +1,134 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"example.com/server/app"
+)
+
+func TestPutUserChangesPassword(t *testing.T) {
+	s := newTestServer(t)
+	token, _ := s.login(t)
+	other, _ := s.login(t)
+	path := "/users/" + s.author.Id
+	ifMatch := fmt.Sprintf(`"%d"`, s.author.Version)
+
+	for name, body := range map[string]map[string]string{
+		"without the current password":  {"password": "Battery-staple-2"},
+		"with a wrong current password": {"password": "Battery-staple-2", "current_password": "Wrong-horse-1"},
+	} {
+		if res := s.do("PUT", path, token, body, "If-Match", ifMatch); res.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want 400: %s", name, res.Code, res.Body)
+		}
+	}
+
+	body := map[string]string{"password": "Battery-staple-2", "current_password": testPassword}
+	if res := s.do("PUT", path, token, body, "If-Match", ifMatch); res.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200: %s", res.Code, res.Body)
+	}
+	if _, err := s.handler.appService.LoginAuthor(context.Background(), s.author.Email, "Battery-staple-2"); err != nil {
+		t.Errorf("logging in with the new password: %v", err)
+	}
+	if res := s.do("GET", "/users/sessions", token, nil); res.Code != http.StatusOK {
+		t.Errorf("the session that changed the password: got %d, want 200", res.Code)
+	}
+	if res := s.do("GET", "/users/sessions", other, nil); res.Code != http.StatusUnauthorized {
+		t.Errorf("another session: got %d, want 401", res.Code)
+	}
+}
+
+func TestPutArticleRate(t *testing.T) {
+	s := newTestServer(t)
+	token, _ := s.login(t)
+	res := s.do("POST", "/articles", token, map[string]interface{}{"title": "Notes", "body": "On the engine.", "rate": 3})
+	if res.Code != http.StatusCreated {
+		t.Fatalf("creating article: got %d: %s", res.Code, res.Body)
+	}
+	var created struct {
+		Article app.Article `json:"article"`
+	}
+	json.Unmarshal(res.Body.Bytes(), &created)
+	path := "/articles/" + created.Article.Id
+
+	for i, tc := range []struct {
+		body map[string]interface{}
+		want int
+	}{
+		{map[string]interface{}{"title": "Sketch"}, 3},
+		{map[string]interface{}{"rate": 0}, 0},
+		{map[string]interface{}{"title": "Draft"}, 0},
+	} {
+		res := s.do("PUT", path, token, tc.body, "If-Match", fmt.Sprintf(`"%d"`, i+1))
+		if res.Code != http.StatusOK {
+			t.Fatalf("PUT %v: got %d: %s", tc.body, res.Code, res.Body)
+		}
+		var updated struct {
+			Article app.Article `json:"article"`
+		}
+		json.Unmarshal(res.Body.Bytes(), &updated)
+		if updated.Article.Rate != tc.want {
+			t.Errorf("PUT %v: rate %d, want %d", tc.body, updated.Article.Rate, tc.want)
+		}
+	}
+}
+
+func TestStalePutArticleLeavesNoRevision(t *testing.T) {
+	s := newTestServer(t)
+	token, _ := s.login(t)
+	res := s.do("POST", "/articles", token, map[string]interface{}{"title": "Notes", "body": "On the engine."})
+	if res.Code != http.StatusCreated {
+		t.Fatalf("creating article: got %d: %s", res.Code, res.Body)
+	}
+	var created struct {
+		Article app.Article `json:"article"`
+	}
+	json.Unmarshal(res.Body.Bytes(), &created)
+	path := "/articles/" + created.Article.Id
+
+	if res := s.do("PUT", path, token, map[string]string{"body": "On the boiler."}, "If-Match", `"1"`); res.Code != http.StatusOK {
+		t.Fatalf("first edit: got %d: %s", res.Code, res.Body)
+	}
+	if res := s.do("PUT", path, token, map[string]string{"body": "On the tender."}, "If-Match", `"1"`); res.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale edit: got %d, want 412: %s", res.Code, res.Body)
+	}
+	res = s.do("GET", path+"/revisions", token, nil)
+	var history struct {
+		Revisions []app.Revision `json:"revisions"`
+	}
+	json.Unmarshal(res.Body.Bytes(), &history)
+	if len(history.Revisions) != 2 {
+		t.Errorf("got %d revisions, want the original and the first edit", len(history.Revisions))
+	}
+}
+
+func TestGetArticleShowsDraftToItsOwner(t *testing.T) {
+	s := newTestServer(t)
+	token, _ := s.login(t)
+	res := s.do("POST", "/articles", token, map[string]interface{}{"title": "Notes", "body": "On the engine."})
+	if res.Code != http.StatusCreated {
+		t.Fatalf("creating article: got %d: %s", res.Code, res.Body)
+	}
+	var created struct {
+		Article app.Article `json:"article"`
+	}
+	json.Unmarshal(res.Body.Bytes(), &created)
+	path := "/articles/" + created.Article.Id
+
+	res = s.do("GET", path, token, nil)
+	if res.Code != http.StatusOK {
+		t.Fatalf("owner: got %d, want 200: %s", res.Code, res.Body)
+	}
+	if etag := res.Header().Get("ETag"); etag != fmt.Sprintf(`"%d"`, created.Article.Version) {
+		t.Errorf("owner: ETag %q", etag)
+	}
+	if res := s.do("GET", path, "", nil); res.Code != http.StatusNotFound {
+		t.Errorf("anonymous: got %d, want 404", res.Code)
+	}
+	if res := s.do("GET", path, "not-a-token", nil); res.Code != http.StatusUnauthorized {
+		t.Errorf("bad token: got %d, want 401", res.Code)
+	}
+}
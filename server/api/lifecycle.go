@@ -0,0 +1,51 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"example.com/server/app"
+	"github.com/gin-gonic/gin"
+)
+
+func (a ginHandler) PublishArticle(c *gin.Context) {
+	a.transitionArticle(c, a.appService.PublishArticle)
+}
+
+func (a ginHandler) UnpublishArticle(c *gin.Context) {
+	a.transitionArticle(c, a.appService.UnpublishArticle)
+}
+
+func (a ginHandler) ArchiveArticle(c *gin.Context) {
+	a.transitionArticle(c, a.appService.ArchiveArticle)
+}
+
+func (a ginHandler) transitionArticle(c *gin.Context, transition func(context.Context, string) (*app.Article, error)) {
+	res, err := transition(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	setETag(c, res.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"article": res,
+	})
+}
+
+// GetDrafts lists an author's unpublished articles, newest first.
+func (a ginHandler) GetDrafts(c *gin.Context) {
+	page, err := pageRequest(c)
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	articles, err := a.appService.ReadDrafts(c.Request.Context(), c.Param("id"), page)
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"articles":    articles.Articles,
+		"next_cursor": articles.NextCursor,
+	})
+}
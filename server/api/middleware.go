@@ -0,0 +1,182 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"example.com/server/app"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	authCookie = "Authorization"
+	sessionKey = "session_id"
+)
+
+// accessTokenTTL is kept short because access tokens are only checked against
+// their session, not rotated; refresh tokens keep the login alive.
+const accessTokenTTL = 15 * time.Minute
+
+var errMissingToken = errors.New("missing authorization token")
+
+// signToken issues the HS256 access token for a session.
+func (a ginHandler) signToken(session *app.Session) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": session.AuthorID,
+		"sid": session.Id,
+		"exp": time.Now().Add(accessTokenTTL).Unix(),
+	})
+	return token.SignedString(a.secret)
+}
+
+// challengeTTL bounds the time between the password and TOTP login steps.
+const challengeTTL = 5 * time.Minute
+
+// signChallenge issues the token that proves the password step of a two-step
+// login. It carries no session, so RequireAuth never accepts it.
+func (a ginHandler) signChallenge(authorID string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": authorID,
+		"typ": "mfa",
+		"exp": time.Now().Add(challengeTTL).Unix(),
+	})
+	return token.SignedString(a.secret)
+}
+
+// parseChallenge validates a token from signChallenge and returns its subject.
+func (a ginHandler) parseChallenge(tokenString string) (string, error) {
+	claims, err := a.parseClaims(tokenString)
+	if err != nil {
+		return "", err
+	}
+	sub, _ := claims["sub"].(string)
+	if typ, _ := claims["typ"].(string); typ != "mfa" || sub == "" {
+		return "", errors.New("not a login challenge")
+	}
+	return sub, nil
+}
+
+// parseToken validates an HS256 token against the secret and returns its subject
+// and session ID.
+func (a ginHandler) parseToken(tokenString string) (string, string, error) {
+	claims, err := a.parseClaims(tokenString)
+	if err != nil {
+		return "", "", err
+	}
+	sub, _ := claims["sub"].(string)
+	sid, _ := claims["sid"].(string)
+	if sub == "" || sid == "" {
+		return "", "", errors.New("token has no subject or session")
+	}
+	return sub, sid, nil
+}
+
+// parseClaims checks an HS256 token's signature against the secret and its expiry.
+func (a ginHandler) parseClaims(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return a.secret, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// requestToken reads the token from an "Authorization: Bearer" header,
+// falling back to the Authorization cookie.
+func requestToken(c *gin.Context) (string, error) {
+	if header := c.GetHeader("Authorization"); header != "" {
+		scheme, token, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			return "", errors.New("malformed authorization header")
+		}
+		return token, nil
+	}
+	if cookie, err := c.Cookie(authCookie); err == nil && cookie != "" {
+		return cookie, nil
+	}
+	return "", errMissingToken
+}
+
+// RequireAuth rejects requests without a valid token for a live session or an
+// API key, and attaches the authenticated app.Author to the request context.
+func (a ginHandler) RequireAuth(c *gin.Context) {
+	tokenString, err := requestToken(c)
+	if err != nil {
+		renderProblem(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if app.IsAPIKey(tokenString) {
+		a.authenticateAPIKey(c, tokenString)
+		return
+	}
+	id, sid, err := a.parseToken(tokenString)
+	if err != nil {
+		renderProblem(c, http.StatusUnauthorized, "invalid authorization token")
+		return
+	}
+	if _, err := a.sessionService.ValidateSession(c.Request.Context(), sid, id); err != nil {
+		renderError(c, err)
+		return
+	}
+	author, err := a.appService.ReadAuthor(c.Request.Context(), id)
+	if err != nil {
+		renderProblem(c, http.StatusUnauthorized, "invalid authorization token")
+		return
+	}
+	c.Set(sessionKey, sid)
+	ctx := app.WithSession(app.NewContext(c.Request.Context(), author), sid)
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+}
+
+// OptionalAuth lets requests without a token through anonymously and
+// authenticates the rest as RequireAuth does, for routes that show signed-in
+// callers more, such as their own drafts.
+func (a ginHandler) OptionalAuth(c *gin.Context) {
+	if _, err := requestToken(c); errors.Is(err, errMissingToken) {
+		c.Next()
+		return
+	}
+	a.RequireAuth(c)
+}
+
+// authenticateAPIKey finishes RequireAuth for an API key, restricting the
+// request to the key's scopes.
+func (a ginHandler) authenticateAPIKey(c *gin.Context, token string) {
+	key, err := a.keyService.AuthenticateAPIKey(c.Request.Context(), token)
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	author, err := a.appService.ReadAuthor(c.Request.Context(), key.AuthorID)
+	if err != nil {
+		renderProblem(c, http.StatusUnauthorized, "invalid api key")
+		return
+	}
+	scopes, err := app.ParseScopes(key.Scopes)
+	if err != nil {
+		renderProblem(c, http.StatusUnauthorized, "invalid api key")
+		return
+	}
+	ctx := app.WithScopes(app.NewContext(c.Request.Context(), author), scopes)
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+}
+
+// currentAuthor returns the author attached by RequireAuth.
+func currentAuthor(c *gin.Context) *app.Author {
+	author, _ := app.AuthorFromContext(c.Request.Context())
+	return author
+}
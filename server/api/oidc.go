@@ -0,0 +1,128 @@
+package http
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"time"
+
+	"example.com/server/app"
+	"example.com/server/oidc"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+// The flow cookie carries the state, nonce and PKCE verifier between the
+// redirect to the issuer and the callback, signed so it cannot be forged.
+const (
+	oidcFlowCookie = "oidc_flow"
+	oidcFlowPath   = "/users/login/oidc"
+	oidcFlowTTL    = 10 * time.Minute
+)
+
+type oidcFlow struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+func (a ginHandler) signFlow(flow oidcFlow) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"typ":      "oidc",
+		"state":    flow.State,
+		"nonce":    flow.Nonce,
+		"verifier": flow.Verifier,
+		"exp":      time.Now().Add(oidcFlowTTL).Unix(),
+	})
+	return token.SignedString(a.secret)
+}
+
+func (a ginHandler) parseFlow(tokenString string) (oidcFlow, error) {
+	claims, err := a.parseClaims(tokenString)
+	if err != nil {
+		return oidcFlow{}, err
+	}
+	var flow oidcFlow
+	typ, _ := claims["typ"].(string)
+	flow.State, _ = claims["state"].(string)
+	flow.Nonce, _ = claims["nonce"].(string)
+	flow.Verifier, _ = claims["verifier"].(string)
+	if typ != "oidc" || flow.State == "" || flow.Nonce == "" || flow.Verifier == "" {
+		return oidcFlow{}, errors.New("not an oidc flow")
+	}
+	return flow, nil
+}
+
+// LoginOIDC starts an authorization code flow by redirecting to the issuer.
+func (a ginHandler) LoginOIDC(c *gin.Context) {
+	var (
+		flow      oidcFlow
+		challenge string
+		err       error
+	)
+	if flow.State, err = oidc.RandomString(); err == nil {
+		if flow.Nonce, err = oidc.RandomString(); err == nil {
+			flow.Verifier, challenge, err = oidc.NewPKCE()
+		}
+	}
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	redirect, err := a.oidcProvider.AuthCodeURL(c.Request.Context(), flow.State, flow.Nonce, challenge)
+	if err != nil {
+		renderProblem(c, http.StatusBadGateway, err.Error())
+		return
+	}
+	cookie, err := a.signFlow(flow)
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcFlowCookie, cookie, int(oidcFlowTTL.Seconds()), oidcFlowPath, "", false, true)
+	c.Redirect(http.StatusFound, redirect)
+}
+
+// LoginOIDCCallback completes the flow, links the identity to an author and
+// starts a session the same way a password login does.
+func (a ginHandler) LoginOIDCCallback(c *gin.Context) {
+	cookie, err := c.Cookie(oidcFlowCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcFlowCookie, "", -1, oidcFlowPath, "", false, true)
+	if err != nil {
+		renderProblem(c, http.StatusBadRequest, "missing login flow")
+		return
+	}
+	flow, err := a.parseFlow(cookie)
+	if err != nil {
+		renderProblem(c, http.StatusBadRequest, "invalid or expired login flow")
+		return
+	}
+	if errParam := c.Query("error"); errParam != "" {
+		renderProblem(c, http.StatusUnauthorized, errParam)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(c.Query("state")), []byte(flow.State)) != 1 {
+		renderProblem(c, http.StatusBadRequest, "state mismatch")
+		return
+	}
+	identity, err := a.oidcProvider.Exchange(c.Request.Context(), c.Query("code"), flow.Verifier, flow.Nonce)
+	if err != nil {
+		renderProblem(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	author, err := a.accountService.LinkIdentity(c.Request.Context(), app.ExternalIdentity{
+		Issuer:        identity.Issuer,
+		Subject:       identity.Subject,
+		Email:         identity.Email,
+		EmailVerified: identity.EmailVerified,
+		FirstName:     identity.GivenName,
+		LastName:      identity.FamilyName,
+	})
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	a.completeLogin(c, author)
+}
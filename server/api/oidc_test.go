@@ -0,0 +1,180 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"example.com/server/app"
+	"example.com/server/oidc"
+	"example.com/server/oidc/oidctest"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// withOIDC adds social login through issuer to the test server.
+func (s *testServer) withOIDC(t *testing.T) *oidctest.Issuer {
+	t.Helper()
+	issuer := oidctest.NewIssuer(t, "modart")
+	s.handler.oidcProvider = oidc.NewProvider(issuer.URL, "modart", "secret", "http://modart.test/users/login/oidc/callback")
+	router, err := newRouter(s.handler, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.router = router
+	return issuer
+}
+
+// startOIDC begins a login and returns the issuer URL it redirects to and
+// the flow cookie it sets.
+func (s *testServer) startOIDC(t *testing.T) (string, *http.Cookie) {
+	t.Helper()
+	res := s.do("GET", "/users/login/oidc", "", nil)
+	if res.Code != http.StatusFound {
+		t.Fatalf("starting OIDC login: got %d: %s", res.Code, res.Body)
+	}
+	for _, cookie := range res.Result().Cookies() {
+		if cookie.Name == oidcFlowCookie {
+			return res.Header().Get("Location"), cookie
+		}
+	}
+	t.Fatal("no flow cookie set")
+	return "", nil
+}
+
+// callback sends the issuer's redirect back to the API.
+func (s *testServer) callback(cookie *http.Cookie, code, state string) *httptest.ResponseRecorder {
+	params := url.Values{"code": {code}, "state": {state}}
+	req := httptest.NewRequest("GET", "/users/login/oidc/callback?"+params.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	res := httptest.NewRecorder()
+	s.router.ServeHTTP(res, req)
+	return res
+}
+
+func TestOIDCLogin(t *testing.T) {
+	s := newTestServer(t)
+	issuer := s.withOIDC(t)
+	authURL, cookie := s.startOIDC(t)
+	code, state := issuer.Authorize(t, authURL, jwt.MapClaims{"email": s.author.Email})
+
+	res := s.callback(cookie, code, state)
+	if res.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200: %s", res.Code, res.Body)
+	}
+	linked, err := s.store.ReadAuthor(s.author.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linked.IdentityIssuer != issuer.URL || linked.IdentitySubject != "subject-1" {
+		t.Errorf("author linked to %q %q", linked.IdentityIssuer, linked.IdentitySubject)
+	}
+}
+
+// TestOIDCLoginSkipsUnverifiedAuthor signs up with someone else's email
+// ahead of them, as an attacker would; their first social login must not
+// land in the attacker's account.
+func TestOIDCLoginSkipsUnverifiedAuthor(t *testing.T) {
+	s := newTestServer(t)
+	issuer := s.withOIDC(t)
+	squatter, err := s.handler.appService.CreateAuthor(context.Background(), &app.Author{
+		FirstName: "Eve",
+		LastName:  "Mallory",
+		Email:     "grace@example.com",
+		Password:  testPassword,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, cookie := s.startOIDC(t)
+	code, state := issuer.Authorize(t, authURL, jwt.MapClaims{"email": "grace@example.com"})
+
+	if res := s.callback(cookie, code, state); res.Code != http.StatusForbidden {
+		t.Errorf("got %d, want 403: %s", res.Code, res.Body)
+	}
+	stored, err := s.store.ReadAuthor(squatter.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.IdentitySubject != "" || stored.EmailVerified {
+		t.Errorf("unverified author linked to %q, verified %v", stored.IdentitySubject, stored.EmailVerified)
+	}
+}
+
+func TestOIDCCallbackRejectsStateMismatch(t *testing.T) {
+	s := newTestServer(t)
+	issuer := s.withOIDC(t)
+	authURL, cookie := s.startOIDC(t)
+	code, _ := issuer.Authorize(t, authURL, nil)
+
+	if res := s.callback(cookie, code, "forged-state"); res.Code != http.StatusBadRequest {
+		t.Errorf("got %d, want 400: %s", res.Code, res.Body)
+	}
+}
+
+func TestOIDCCallbackRejectsMissingFlow(t *testing.T) {
+	s := newTestServer(t)
+	issuer := s.withOIDC(t)
+	authURL, _ := s.startOIDC(t)
+	code, state := issuer.Authorize(t, authURL, nil)
+
+	if res := s.callback(nil, code, state); res.Code != http.StatusBadRequest {
+		t.Errorf("without the flow cookie: got %d, want 400", res.Code)
+	}
+	forged := &http.Cookie{Name: oidcFlowCookie, Value: "forged"}
+	if res := s.callback(forged, code, state); res.Code != http.StatusBadRequest {
+		t.Errorf("with a forged flow cookie: got %d, want 400", res.Code)
+	}
+}
+
+func TestOIDCCallbackRejectsNonceMismatch(t *testing.T) {
+	s := newTestServer(t)
+	issuer := s.withOIDC(t)
+	authURL, cookie := s.startOIDC(t)
+	code, state := issuer.Authorize(t, authURL, jwt.MapClaims{"nonce": "from-another-login"})
+
+	if res := s.callback(cookie, code, state); res.Code != http.StatusUnauthorized {
+		t.Errorf("got %d, want 401: %s", res.Code, res.Body)
+	}
+}
+
+// TestOIDCCallbackRejectsInjectedCode redeems a code issued to one login
+// with another login's flow, as an attacker injecting a stolen code would;
+// the issuer refuses it because the PKCE verifier does not match.
+func TestOIDCCallbackRejectsInjectedCode(t *testing.T) {
+	s := newTestServer(t)
+	issuer := s.withOIDC(t)
+	stolenURL, _ := s.startOIDC(t)
+	stolen, _ := issuer.Authorize(t, stolenURL, nil)
+	_, cookie := s.startOIDC(t)
+	flow, err := s.handler.parseFlow(cookie.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res := s.callback(cookie, stolen, flow.State); res.Code != http.StatusUnauthorized {
+		t.Errorf("got %d, want 401: %s", res.Code, res.Body)
+	}
+}
+
+func TestOIDCCallbackRejectsBadSignature(t *testing.T) {
+	s := newTestServer(t)
+	issuer := s.withOIDC(t)
+	forger, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.SigningKey = forger
+	authURL, cookie := s.startOIDC(t)
+	code, state := issuer.Authorize(t, authURL, nil)
+
+	if res := s.callback(cookie, code, state); res.Code != http.StatusUnauthorized {
+		t.Errorf("got %d, want 401: %s", res.Code, res.Body)
+	}
+}
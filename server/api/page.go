@@ -0,0 +1,64 @@
+package http
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"example.com/server/app"
+	"github.com/gin-gonic/gin"
+)
+
+// pageRequest reads the ?limit= and ?cursor= of a listing. Range checks are
+// left to the service.
+func pageRequest(c *gin.Context) (app.PageRequest, error) {
+	page := app.PageRequest{Cursor: c.Query("cursor")}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return page, app.NewValidationError(app.FieldError{Field: "limit", Message: "must be a number"})
+		}
+		page.Limit = n
+	}
+	return page, nil
+}
+
+// articleQuery reads the filters and sort of GET /articles. Times are
+// RFC 3339.
+func articleQuery(c *gin.Context) (app.ArticleQuery, error) {
+	query := app.ArticleQuery{AuthorID: c.Query("author_id")}
+	var fields []app.FieldError
+	if rate := c.Query("min_rate"); rate != "" {
+		n, err := strconv.Atoi(rate)
+		if err != nil {
+			fields = append(fields, app.FieldError{Field: "min_rate", Message: "must be a number"})
+		}
+		query.MinRate = n
+	}
+	for _, bound := range []struct {
+		name string
+		dst  *int64
+	}{{"since", &query.Since}, {"until", &query.Until}} {
+		if value := c.Query(bound.name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				fields = append(fields, app.FieldError{Field: bound.name, Message: "must be an RFC 3339 time"})
+				continue
+			}
+			*bound.dst = t.Unix()
+		}
+	}
+	sort, err := app.ParseSort(c.Query("sort"))
+	var invalid *app.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		fields = append(fields, invalid.Fields...)
+	case err != nil:
+		return query, err
+	}
+	query.Sort = sort
+	if len(fields) > 0 {
+		return query, app.NewValidationError(fields...)
+	}
+	return query, nil
+}
@@ -0,0 +1,30 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestGetArticlesReportsEveryInvalidParameter(t *testing.T) {
+	s := newTestServer(t)
+	res := s.do("GET", "/articles?min_rate=high&since=yesterday&sort=title", "", nil)
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400: %s", res.Code, res.Body)
+	}
+	var problem struct {
+		Errors []struct {
+			Field string `json:"field"`
+		} `json:"errors"`
+	}
+	json.Unmarshal(res.Body.Bytes(), &problem)
+	got := map[string]bool{}
+	for _, field := range problem.Errors {
+		got[field.Field] = true
+	}
+	for _, want := range []string{"min_rate", "since", "sort"} {
+		if !got[want] {
+			t.Errorf("no error for %s in %s", want, res.Body)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"example.com/server/app"
+	"github.com/gin-gonic/gin"
+)
+
+// revisionNumber parses a revision number from the named path or query
+// parameter.
+func revisionNumber(name, value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, app.NewValidationError(app.FieldError{Field: name, Message: "must be a revision number"})
+	}
+	return n, nil
+}
+
+func (a ginHandler) GetRevisions(c *gin.Context) {
+	revisions, err := a.appService.ReadRevisions(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"revisions": revisions,
+	})
+}
+
+func (a ginHandler) GetRevision(c *gin.Context) {
+	number, err := revisionNumber("rev", c.Param("rev"))
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	revision, err := a.appService.ReadRevision(c.Request.Context(), c.Param("id"), number)
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"revision": revision,
+	})
+}
+
+// DiffRevisions returns the unified diff between revisions ?from= and ?to=.
+func (a ginHandler) DiffRevisions(c *gin.Context) {
+	from, err := revisionNumber("from", c.Query("from"))
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	to, err := revisionNumber("to", c.Query("to"))
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	diff, err := a.appService.DiffRevisions(c.Request.Context(), c.Param("id"), from, to)
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"from": from,
+		"to":   to,
+		"diff": diff,
+	})
+}
+
+func (a ginHandler) RestoreRevision(c *gin.Context) {
+	number, err := revisionNumber("rev", c.Param("rev"))
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	res, err := a.appService.RestoreRevision(c.Request.Context(), c.Param("id"), number)
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	setETag(c, res.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"article": res,
+	})
+}
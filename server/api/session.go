@@ -0,0 +1,103 @@
+package http
+
+import (
+	"net/http"
+
+	"example.com/server/app"
+	"github.com/gin-gonic/gin"
+)
+
+// refreshCookie is scoped to /users so it is only sent to the refresh and
+// logout endpoints.
+const (
+	refreshCookie = "Refresh"
+	refreshPath   = "/users"
+)
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// issueTokens signs an access token for session and hands both tokens to the
+// client as cookies and in the response body.
+func (a ginHandler) issueTokens(c *gin.Context, session *app.Session, refreshToken, message string) {
+	accessToken, err := a.signToken(session)
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(authCookie, accessToken, int(accessTokenTTL.Seconds()), "/", "", false, true)
+	c.SetCookie(refreshCookie, refreshToken, int(app.SessionTTL.Seconds()), refreshPath, "", false, true)
+	c.JSON(http.StatusOK, gin.H{
+		"message":       message,
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"expires_in":    int(accessTokenTTL.Seconds()),
+	})
+}
+
+func clearTokens(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(authCookie, "", -1, "/", "", false, true)
+	c.SetCookie(refreshCookie, "", -1, refreshPath, "", false, true)
+}
+
+func (a ginHandler) RefreshSession(c *gin.Context) {
+	var req refreshRequest
+	if cookie, err := c.Cookie(refreshCookie); err == nil {
+		req.RefreshToken = cookie
+	}
+	if req.RefreshToken == "" {
+		if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+			renderProblem(c, http.StatusUnauthorized, "missing refresh token")
+			return
+		}
+	}
+	session, refreshToken, err := a.sessionService.RefreshSession(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		clearTokens(c)
+		renderError(c, err)
+		return
+	}
+	a.issueTokens(c, session, refreshToken, "session refreshed")
+}
+
+func (a ginHandler) LogoutUser(c *gin.Context) {
+	err := a.sessionService.RevokeSession(c.Request.Context(), c.GetString(sessionKey))
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	clearTokens(c)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "logout successful",
+	})
+}
+
+func (a ginHandler) GetSessions(c *gin.Context) {
+	sessions, err := a.sessionService.ReadSessions(c.Request.Context())
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": sessions,
+		"current":  c.GetString(sessionKey),
+	})
+}
+
+func (a ginHandler) DeleteSession(c *gin.Context) {
+	sid := c.Param("sid")
+	err := a.sessionService.RevokeSession(c.Request.Context(), sid)
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+	if sid == c.GetString(sessionKey) {
+		clearTokens(c)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "session revoked",
+	})
+}
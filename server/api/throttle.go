@@ -0,0 +1,38 @@
+package http
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// allowLogin responds 429 with Retry-After and returns false when the client
+// IP or subject is throttled.
+func (a ginHandler) allowLogin(c *gin.Context, subject string) bool {
+	wait, err := a.loginThrottle.Check(c.Request.Context(), c.ClientIP(), subject)
+	if err != nil {
+		renderError(c, err)
+		return false
+	}
+	if wait > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		renderProblem(c, http.StatusTooManyRequests, "too many failed login attempts, try again later")
+		return false
+	}
+	return true
+}
+
+func (a ginHandler) loginFailed(c *gin.Context, subject, reason string) {
+	if err := a.loginThrottle.Fail(c.Request.Context(), c.ClientIP(), subject, reason); err != nil {
+		log.Printf("recording failed login for %q: %v", subject, err)
+	}
+}
+
+func (a ginHandler) loginSucceeded(c *gin.Context, subject string) {
+	if err := a.loginThrottle.Succeed(c.Request.Context(), c.ClientIP(), subject); err != nil {
+		log.Printf("clearing failed logins for %q: %v", subject, err)
+	}
+}
@@ -0,0 +1,38 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestClientIPTrustsOnlyConfiguredProxies checks the address logins are
+// throttled by: a client must not pick its own with X-Forwarded-For.
+func TestClientIPTrustsOnlyConfiguredProxies(t *testing.T) {
+	s := newTestServer(t)
+	for name, tc := range map[string]struct {
+		trustedProxies []string
+		want           string
+	}{
+		"no proxies":    {nil, "192.0.2.1"},
+		"other proxy":   {[]string{"198.51.100.7"}, "192.0.2.1"},
+		"trusted proxy": {[]string{"192.0.2.0/24"}, "203.0.113.9"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			router, err := newRouter(s.handler, false, tc.trustedProxies)
+			if err != nil {
+				t.Fatal(err)
+			}
+			router.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+			req := httptest.NewRequest("GET", "/ip", nil)
+			req.Header.Set("X-Forwarded-For", "203.0.113.9")
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, req)
+			if got := res.Body.String(); got != tc.want {
+				t.Errorf("client IP %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package app
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	errs "github.com/pkg/errors"
+)
+
+const (
+	VerificationTokenTTL  = 48 * time.Hour
+	PasswordResetTokenTTL = time.Hour
+)
+
+const (
+	purposeVerifyEmail   = "verify-email"
+	purposeResetPassword = "reset-password"
+)
+
+type accountService struct {
+	appRepo     AppRepository
+	sessionRepo SessionRepository
+	mailer      Mailer
+	secret      []byte
+	baseURL     string
+}
+
+// NewAccountService builds the verification and password reset flows. Links
+// in emails point at baseURL, and tokens are signed with secret.
+func NewAccountService(appRepo AppRepository, sessionRepo SessionRepository, mailer Mailer, secret []byte, baseURL string) AccountService {
+	return &accountService{
+		appRepo:     appRepo,
+		sessionRepo: sessionRepo,
+		mailer:      mailer,
+		secret:      secret,
+		baseURL:     strings.TrimRight(baseURL, "/"),
+	}
+}
+
+func (s *accountService) SendVerification(ctx context.Context, author *Author) error {
+	if author.EmailVerified {
+		return nil
+	}
+	token := s.signToken(purposeVerifyEmail, author.Id, author.Email, VerificationTokenTTL)
+	return s.mailer.Send(ctx, Message{
+		To:      author.Email,
+		Subject: "Verify your Modart email address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm your email address by opening the link below within %s:\n\n%s/verify-email?token=%s\n",
+			author.FirstName, VerificationTokenTTL, s.baseURL, token),
+	})
+}
+
+func (s *accountService) VerifyEmail(ctx context.Context, token string) error {
+	author, err := s.checkToken(purposeVerifyEmail, token, func(a *Author) string {
+		return a.Email
+	})
+	if err != nil {
+		return errs.Wrap(err, "service.Account.VerifyEmail")
+	}
+	if author.EmailVerified {
+		return errs.Wrap(ErrInvalidToken, "service.Account.VerifyEmail")
+	}
+	author.EmailVerified = true
+	_, err = s.appRepo.UpdateAuthor(author)
+	return err
+}
+
+func (s *accountService) RequestPasswordReset(ctx context.Context, email string) error {
+	author, err := s.appRepo.ReadAuthorByEmail(email)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			// Unknown emails succeed silently so the endpoint cannot be used to
+			// discover accounts.
+			return nil
+		}
+		return err
+	}
+	token := s.signToken(purposeResetPassword, author.Id, author.Password, PasswordResetTokenTTL)
+	return s.mailer.Send(ctx, Message{
+		To:      author.Email,
+		Subject: "Reset your Modart password",
+		Body: fmt.Sprintf("Hi %s,\n\nChoose a new password by opening the link below within %s:\n\n%s/reset-password?token=%s\n\nIf you did not ask for this, you can ignore this email.\n",
+			author.FirstName, PasswordResetTokenTTL, s.baseURL, token),
+	})
+}
+
+func (s *accountService) ResetPassword(ctx context.Context, token, password string) error {
+	if weak := passwordProblem(password); weak != nil {
+		return errs.Wrap(NewValidationError(*weak), "service.Account.ResetPassword")
+	}
+	author, err := s.checkToken(purposeResetPassword, token, func(a *Author) string {
+		return a.Password
+	})
+	if err != nil {
+		return errs.Wrap(err, "service.Account.ResetPassword")
+	}
+	author.Password = password
+	if err := hashPassword(author); err != nil {
+		return err
+	}
+	if _, err := s.appRepo.UpdateAuthor(author); err != nil {
+		return err
+	}
+	return revokeSessions(s.sessionRepo, author.Id, "")
+}
+
+// revokeSessions logs an author out everywhere but the session keep after
+// their password changes.
+func revokeSessions(sessionRepo SessionRepository, authorID, keep string) error {
+	sessions, err := sessionRepo.ReadSessions(authorID)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	for _, session := range sessions {
+		if !session.Active(now) || session.Id == keep {
+			continue
+		}
+		session.RevokedAt = now.Unix()
+		if _, err := sessionRepo.UpdateSession(session); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// signToken returns a stateless token for purpose. The token is bound to a
+// fingerprint of state that using it changes (the email's verification, the
+// password hash), which makes it single-use without storing it.
+func (s *accountService) signToken(purpose, authorID, state string, ttl time.Duration) string {
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	payload := strings.Join([]string{purpose, authorID, expires, fingerprint(state)}, "|")
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + s.mac(encoded)
+}
+
+// checkToken verifies a token of purpose and returns its author, rejecting it
+// when state(author) no longer matches the fingerprint it was issued for.
+func (s *accountService) checkToken(purpose, token string, state func(*Author) string) (*Author, error) {
+	encoded, mac, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(mac), []byte(s.mac(encoded))) {
+		return nil, ErrInvalidToken
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 4 || parts[0] != purpose {
+		return nil, ErrInvalidToken
+	}
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || time.Now().Unix() >= expires {
+		return nil, ErrInvalidToken
+	}
+	author, err := s.appRepo.ReadAuthor(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(parts[3]), []byte(fingerprint(state(author)))) {
+		return nil, ErrInvalidToken
+	}
+	return author, nil
+}
+
+func (s *accountService) mac(data string) string {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+func fingerprint(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ExternalIdentity is an account at an OpenID Connect provider.
+type ExternalIdentity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+}
+
+// LinkIdentity returns the author for an external identity. Authors are
+// matched by verified email and linked on first use; unknown emails get a new
+// author. An author stays linked to the first identity it was matched with.
+// An author whose own email was never verified is not linked: anyone could
+// have signed up with that address, and linking would hand the identity's
+// owner an account its creator can still log into.
+func (s *accountService) LinkIdentity(ctx context.Context, identity ExternalIdentity) (*Author, error) {
+	if identity.Subject == "" || identity.Email == "" || !identity.EmailVerified {
+		return nil, errs.Wrap(ErrEmailUnverified, "service.Account.LinkIdentity")
+	}
+	author, err := s.appRepo.ReadAuthorByEmail(identity.Email)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		// The password is random and never revealed; the author can set one
+		// through the password reset flow.
+		password, err := newTokenSecret()
+		if err != nil {
+			return nil, err
+		}
+		author := &Author{
+			FirstName:       identity.FirstName,
+			LastName:        identity.LastName,
+			Email:           identity.Email,
+			Password:        password,
+			Role:            DefaultRole,
+			EmailVerified:   true,
+			IdentityIssuer:  identity.Issuer,
+			IdentitySubject: identity.Subject,
+		}
+		if err := hashPassword(author); err != nil {
+			return nil, err
+		}
+		return s.appRepo.CreateAuthor(author)
+	}
+	if author.IdentitySubject != "" {
+		if author.IdentityIssuer != identity.Issuer || author.IdentitySubject != identity.Subject {
+			return nil, errs.Wrap(ErrForbidden, "service.Account.LinkIdentity")
+		}
+		return author, nil
+	}
+	if !author.EmailVerified {
+		return nil, errs.Wrap(ErrEmailUnverified, "service.Account.LinkIdentity: matching author is unverified")
+	}
+	author.IdentityIssuer = identity.Issuer
+	author.IdentitySubject = identity.Subject
+	return s.appRepo.UpdateAuthor(author)
+}
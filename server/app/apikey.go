@@ -0,0 +1,173 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	errs "github.com/pkg/errors"
+)
+
+// Scope limits what an API key can do on behalf of its author.
+type Scope string
+
+const (
+	ScopeRead          Scope = "read"
+	ScopeWriteArticles Scope = "articles:write"
+)
+
+// apiKeyPrefix marks API keys so they can be told apart from session tokens.
+const apiKeyPrefix = "mdk_"
+
+// apiKeyTouchInterval limits how often LastUsedAt is written.
+const apiKeyTouchInterval = time.Minute
+
+// scopePermissions lists what each scope allows. Account management is never
+// available to API keys.
+var scopePermissions = map[Scope][]Permission{
+	ScopeRead: {
+		PermReadArticles,
+		PermReadAuthors,
+	},
+	ScopeWriteArticles: {
+		PermReadArticles,
+		PermWriteOwnArticles,
+		PermEditAnyArticle,
+		PermUnpublishAnyArticle,
+		PermDeleteAnyArticle,
+	},
+}
+
+// ParseScopes parses a space separated scope list.
+func ParseScopes(s string) ([]Scope, error) {
+	scopes := []Scope{}
+	for _, field := range strings.Fields(s) {
+		scope := Scope(field)
+		if _, ok := scopePermissions[scope]; !ok {
+			return nil, errs.Wrap(ErrInvalid, fmt.Sprintf("unknown scope %q", field))
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes, nil
+}
+
+// IsAPIKey reports whether token has the shape of an API key.
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyPrefix)
+}
+
+func scopesAllow(scopes []Scope, p Permission) bool {
+	for _, scope := range scopes {
+		if hasPermission(scopePermissions[scope], p) {
+			return true
+		}
+	}
+	return false
+}
+
+type keyService struct {
+	keyRepo APIKeyRepository
+}
+
+func NewKeyService(keyRepo APIKeyRepository) KeyService {
+	return &keyService{
+		keyRepo,
+	}
+}
+
+func (s *keyService) CreateAPIKey(ctx context.Context, authorID, name string, scopes []Scope) (*APIKey, string, error) {
+	actor, ok := AuthorFromContext(ctx)
+	if !ok {
+		return nil, "", errs.Wrap(ErrUnauthenticated, "service.APIKey.Create")
+	}
+	if actor.Id != authorID || !can(ctx, PermManageOwnAccount) {
+		return nil, "", errs.Wrap(ErrForbidden, "service.APIKey.Create")
+	}
+	if strings.TrimSpace(name) == "" || len(scopes) == 0 {
+		return nil, "", errs.Wrap(ErrInvalid, "service.APIKey.Create")
+	}
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		if _, ok := scopePermissions[scope]; !ok {
+			return nil, "", errs.Wrap(ErrInvalid, "service.APIKey.Create")
+		}
+		names[i] = string(scope)
+	}
+	secret, err := newTokenSecret()
+	if err != nil {
+		return nil, "", err
+	}
+	key := &APIKey{
+		Id:       uuid.New().String(),
+		AuthorID: authorID,
+		Name:     strings.TrimSpace(name),
+		Scopes:   strings.Join(names, " "),
+		KeyHash:  hashToken(secret),
+		CreateAt: time.Now().UTC().Unix(),
+	}
+	key, err = s.keyRepo.CreateAPIKey(key)
+	if err != nil {
+		return nil, "", err
+	}
+	return key, apiKeyPrefix + key.Id + "_" + secret, nil
+}
+
+func (s *keyService) ReadAPIKeys(ctx context.Context, authorID string) ([]*APIKey, error) {
+	if err := authorizeOwned(ctx, authorID, PermManageOwnAccount, PermManageAuthors); err != nil {
+		return nil, errs.Wrap(err, "service.APIKey.Read")
+	}
+	return s.keyRepo.ReadAPIKeys(authorID)
+}
+
+func (s *keyService) RevokeAPIKey(ctx context.Context, authorID, id string) error {
+	if err := authorizeOwned(ctx, authorID, PermManageOwnAccount, PermManageAuthors); err != nil {
+		return errs.Wrap(err, "service.APIKey.Revoke")
+	}
+	key, err := s.keyRepo.ReadAPIKey(id)
+	if err != nil {
+		return err
+	}
+	if key.AuthorID != authorID {
+		return errs.Wrap(ErrNotFound, "service.APIKey.Revoke")
+	}
+	if key.RevokedAt != 0 {
+		return nil
+	}
+	key.RevokedAt = time.Now().UTC().Unix()
+	_, err = s.keyRepo.UpdateAPIKey(key)
+	return err
+}
+
+func (s *keyService) AuthenticateAPIKey(ctx context.Context, token string) (*APIKey, error) {
+	rest := strings.TrimPrefix(token, apiKeyPrefix)
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || rest == token {
+		return nil, errs.Wrap(ErrInvalidToken, "service.APIKey.Authenticate")
+	}
+	key, err := s.keyRepo.ReadAPIKey(id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, errs.Wrap(ErrInvalidToken, "service.APIKey.Authenticate")
+		}
+		return nil, err
+	}
+	if key.RevokedAt != 0 || subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(hashToken(secret))) != 1 {
+		return nil, errs.Wrap(ErrInvalidToken, "service.APIKey.Authenticate")
+	}
+	now := time.Now().UTC()
+	if now.Sub(time.Unix(key.LastUsedAt, 0)) > apiKeyTouchInterval {
+		if err := s.keyRepo.TouchAPIKey(key.Id, now.Unix()); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				// Revoked since it was read.
+				return nil, errs.Wrap(ErrInvalidToken, "service.APIKey.Authenticate")
+			}
+			return nil, err
+		}
+		key.LastUsedAt = now.Unix()
+	}
+	return key, nil
+}
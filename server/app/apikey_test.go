@@ -0,0 +1,87 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"example.com/server/app"
+	"example.com/server/repository"
+)
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	store := repository.NewMemoryDB()
+	keys := app.NewKeyService(store)
+	ctx := newAuthorContext(t, store, "ada@example.com")
+	author, _ := app.AuthorFromContext(ctx)
+
+	key, token, err := keys.CreateAPIKey(ctx, author.Id, "deploy", []app.Scope{app.ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !app.IsAPIKey(token) {
+		t.Errorf("token %q does not look like an API key", token)
+	}
+	authenticated, err := keys.AuthenticateAPIKey(context.Background(), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authenticated.Id != key.Id || authenticated.LastUsedAt == 0 {
+		t.Errorf("authenticated %+v", authenticated)
+	}
+	if _, err := keys.AuthenticateAPIKey(context.Background(), token+"x"); !errors.Is(err, app.ErrInvalidToken) {
+		t.Errorf("wrong secret: got %v, want ErrInvalidToken", err)
+	}
+
+	other := newAuthorContext(t, store, "grace@example.com")
+	if err := keys.RevokeAPIKey(other, author.Id, key.Id); !errors.Is(err, app.ErrForbidden) {
+		t.Errorf("revoking another author's key: got %v, want ErrForbidden", err)
+	}
+	if err := keys.RevokeAPIKey(ctx, author.Id, key.Id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keys.AuthenticateAPIKey(context.Background(), token); !errors.Is(err, app.ErrInvalidToken) {
+		t.Errorf("revoked key: got %v, want ErrInvalidToken", err)
+	}
+}
+
+// revokingRepository revokes each key right after it is read, as a
+// revocation landing between AuthenticateAPIKey's read and write would.
+type revokingRepository struct {
+	app.APIKeyRepository
+}
+
+func (r revokingRepository) ReadAPIKey(id string) (*app.APIKey, error) {
+	key, err := r.APIKeyRepository.ReadAPIKey(id)
+	if err != nil {
+		return nil, err
+	}
+	revoked := *key
+	revoked.RevokedAt = 1
+	if _, err := r.UpdateAPIKey(&revoked); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func TestAuthenticateAPIKeyKeepsConcurrentRevocation(t *testing.T) {
+	store := repository.NewMemoryDB()
+	ctx := newAuthorContext(t, store, "ada@example.com")
+	author, _ := app.AuthorFromContext(ctx)
+	key, token, err := app.NewKeyService(store).CreateAPIKey(ctx, author.Id, "deploy", []app.Scope{app.ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := app.NewKeyService(revokingRepository{store})
+	if _, err := keys.AuthenticateAPIKey(context.Background(), token); !errors.Is(err, app.ErrInvalidToken) {
+		t.Errorf("got %v, want ErrInvalidToken", err)
+	}
+	stored, err := store.ReadAPIKey(key.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.RevokedAt == 0 {
+		t.Error("the revocation was written over")
+	}
+}
@@ -0,0 +1,44 @@
+package app
+
+import "context"
+
+type contextKey int
+
+const (
+	authorContextKey contextKey = iota
+	scopesContextKey
+	sessionContextKey
+)
+
+// NewContext returns a copy of ctx carrying the author acting on the request.
+func NewContext(ctx context.Context, author *Author) context.Context {
+	return context.WithValue(ctx, authorContextKey, author)
+}
+
+// AuthorFromContext returns the acting author stored by NewContext.
+func AuthorFromContext(ctx context.Context) (*Author, bool) {
+	author, ok := ctx.Value(authorContextKey).(*Author)
+	return author, ok && author != nil
+}
+
+// WithScopes restricts the actor in ctx to scopes, as for API key requests.
+// Contexts without scopes are unrestricted.
+func WithScopes(ctx context.Context, scopes []Scope) context.Context {
+	return context.WithValue(ctx, scopesContextKey, scopes)
+}
+
+func scopesFromContext(ctx context.Context) ([]Scope, bool) {
+	scopes, ok := ctx.Value(scopesContextKey).([]Scope)
+	return scopes, ok
+}
+
+// WithSession records the ID of the session the request was made in, which
+// account changes that log out other sessions keep.
+func WithSession(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionContextKey, sessionID)
+}
+
+func sessionFromContext(ctx context.Context) string {
+	sessionID, _ := ctx.Value(sessionContextKey).(string)
+	return sessionID
+}
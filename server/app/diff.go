@@ -0,0 +1,244 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is how many unchanged lines surround each hunk.
+const diffContext = 3
+
+type diffOp int
+
+const (
+	opEqual diffOp = iota
+	opDelete
+	opInsert
+)
+
+// diffLine is one line of an edit script. a and b are the line's index in
+// the old and new text, before the line when it is absent from that side.
+type diffLine struct {
+	op   diffOp
+	text string
+	a, b int
+}
+
+// unifiedDiff renders the changes from oldText to newText in unified diff
+// format, with fromName and toName in the file headers. It is empty when the
+// texts are equal.
+func unifiedDiff(fromName, toName, oldText, newText string) string {
+	lines := diffLines(splitLines(oldText), splitLines(newText))
+	var out strings.Builder
+	for _, hunk := range hunks(lines) {
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		writeHunk(&out, hunk)
+	}
+	return out.String()
+}
+
+// splitLines splits text into lines, treating a final newline as ending
+// the last line rather than starting an empty one.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffMaxCost bounds the work diffLines does on texts that have little in
+// common: once finding the shortest edit script would take more than this
+// many edits on each side of its middle, the differing span is reported as
+// deleted and inserted whole. It keeps a diff of two large bodies to
+// O((N+M)·diffMaxCost) time.
+const diffMaxCost = 1000
+
+// diffLines returns an edit script turning a into b. It is the shortest one,
+// found by the linear-space variant of Myers' O(ND) difference algorithm,
+// unless that would cost more than diffMaxCost allows.
+func diffLines(a, b []string) []diffLine {
+	size := (len(a)+len(b)+1)/2 + 1
+	d := &differ{a: a, b: b, offset: size, vf: make([]int, 2*size+1), vb: make([]int, 2*size+1)}
+	d.diff(0, len(a), 0, len(b))
+	return d.script
+}
+
+// differ holds the state of one diffLines call: the texts, the script built
+// so far, and the forward and backward frontiers the middle snake search
+// reuses, indexed by diagonal plus offset.
+type differ struct {
+	a, b   []string
+	script []diffLine
+	offset int
+	vf, vb []int
+}
+
+// diff appends the edit script turning a[aLo:aHi] into b[bLo:bHi].
+func (d *differ) diff(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.script = append(d.script, diffLine{opEqual, d.a[aLo], aLo, bLo})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-suffix-1] == d.b[bHi-suffix-1] {
+		suffix++
+	}
+	aHi -= suffix
+	bHi -= suffix
+
+	if aLo < aHi && bLo < bHi {
+		if x, y, u, v, ok := d.middleSnake(aLo, aHi, bLo, bHi); ok {
+			d.diff(aLo, x, bLo, y)
+			for ; x < u; x, y = x+1, y+1 {
+				d.script = append(d.script, diffLine{opEqual, d.a[x], x, y})
+			}
+			d.diff(u, aHi, v, bHi)
+		} else {
+			d.replace(aLo, aHi, bLo, bHi)
+		}
+	} else {
+		d.replace(aLo, aHi, bLo, bHi)
+	}
+
+	for i := 0; i < suffix; i++ {
+		d.script = append(d.script, diffLine{opEqual, d.a[aHi+i], aHi + i, bHi + i})
+	}
+}
+
+// replace appends the deletion of a[aLo:aHi] and the insertion of
+// b[bLo:bHi].
+func (d *differ) replace(aLo, aHi, bLo, bHi int) {
+	for x := aLo; x < aHi; x++ {
+		d.script = append(d.script, diffLine{opDelete, d.a[x], x, bLo})
+	}
+	for y := bLo; y < bHi; y++ {
+		d.script = append(d.script, diffLine{opInsert, d.b[y], aHi, y})
+	}
+}
+
+// middleSnake finds the snake in the middle of a shortest edit script
+// turning a[aLo:aHi] into b[bLo:bHi], both non-empty, by searching forward
+// from the start and backward from the end until the two meet. It returns
+// the snake as running from (x, y) to (u, v), and false once the search
+// passes diffMaxCost edits.
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (x, y, u, v int, ok bool) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	vf, vb, off := d.vf, d.vb, d.offset
+	vf[off+1], vb[off+1] = 0, 0
+	for cost := 0; cost <= (n+m+1)/2 && cost <= diffMaxCost; cost++ {
+		// Forward, along diagonals k = x - y.
+		for k := -cost; k <= cost; k += 2 {
+			var x int
+			if k == -cost || (k != cost && vf[off+k-1] < vf[off+k+1]) {
+				x = vf[off+k+1]
+			} else {
+				x = vf[off+k-1] + 1
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			vf[off+k] = x
+			// The backward search runs along diagonals c = delta - k of the
+			// reversed texts.
+			if c := delta - k; odd && c >= -(cost-1) && c <= cost-1 && x+vb[off+c] >= n {
+				return aLo + x0, bLo + y0, aLo + x, bLo + y, true
+			}
+		}
+		// Backward, in the reversed texts.
+		for c := -cost; c <= cost; c += 2 {
+			var x int
+			if c == -cost || (c != cost && vb[off+c-1] < vb[off+c+1]) {
+				x = vb[off+c+1]
+			} else {
+				x = vb[off+c-1] + 1
+			}
+			y := x - c
+			x0, y0 := x, y
+			for x < n && y < m && d.a[aHi-1-x] == d.b[bHi-1-y] {
+				x++
+				y++
+			}
+			vb[off+c] = x
+			if k := delta - c; !odd && k >= -cost && k <= cost && x+vf[off+k] >= n {
+				return aHi - x, bHi - y, aHi - x0, bHi - y0, true
+			}
+		}
+	}
+	return 0, 0, 0, 0, false
+}
+
+// hunks groups the changes in lines with their surrounding context, merging
+// changes whose context would overlap.
+func hunks(lines []diffLine) [][]diffLine {
+	var result [][]diffLine
+	start, end := -1, -1
+	for i, line := range lines {
+		if line.op == opEqual {
+			continue
+		}
+		lo, hi := i-diffContext, i+diffContext+1
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > len(lines) {
+			hi = len(lines)
+		}
+		if start >= 0 && lo > end {
+			result = append(result, lines[start:end])
+			start = -1
+		}
+		if start < 0 {
+			start = lo
+		}
+		end = hi
+	}
+	if start >= 0 {
+		result = append(result, lines[start:end])
+	}
+	return result
+}
+
+func writeHunk(out *strings.Builder, hunk []diffLine) {
+	oldCount, newCount := 0, 0
+	for _, line := range hunk {
+		if line.op != opInsert {
+			oldCount++
+		}
+		if line.op != opDelete {
+			newCount++
+		}
+	}
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(hunk[0].a, oldCount), hunkRange(hunk[0].b, newCount))
+	for _, line := range hunk {
+		switch line.op {
+		case opEqual:
+			out.WriteString(" ")
+		case opDelete:
+			out.WriteString("-")
+		case opInsert:
+			out.WriteString("+")
+		}
+		out.WriteString(line.text)
+		out.WriteString("\n")
+	}
+}
+
+// hunkRange formats a hunk's start line and length. An empty range names the
+// line before it, as diff and patch expect.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
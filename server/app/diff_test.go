@@ -0,0 +1,118 @@
+package app
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	old := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	new := "one\ntwo\nthree\nfour\nFIVE\nsix\nseven\neight\nnine\nten\neleven\n"
+	want := `--- before
++++ after
+@@ -2,9 +2,10 @@
+ two
+ three
+ four
+-five
++FIVE
+ six
+ seven
+ eight
+ nine
+ ten
++eleven
+`
+	if got := unifiedDiff("before", "after", old, new); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if got := unifiedDiff("before", "after", old, old); got != "" {
+		t.Errorf("diff of equal texts: %q", got)
+	}
+	if got := unifiedDiff("before", "after", "", "only\n"); got != "--- before\n+++ after\n@@ -0,0 +1 @@\n+only\n" {
+		t.Errorf("diff from empty: %q", got)
+	}
+}
+
+// apply checks that script turns a into b, and returns how many lines it
+// deletes or inserts.
+func apply(t *testing.T, script []diffLine, a, b []string) int {
+	t.Helper()
+	var gotA, gotB []string
+	edits := 0
+	for _, line := range script {
+		if line.op != opInsert {
+			gotA = append(gotA, line.text)
+		}
+		if line.op != opDelete {
+			gotB = append(gotB, line.text)
+		}
+		if line.op != opEqual {
+			edits++
+		}
+	}
+	if strings.Join(gotA, "\n") != strings.Join(a, "\n") || strings.Join(gotB, "\n") != strings.Join(b, "\n") {
+		t.Fatalf("script does not turn %q into %q", a, b)
+	}
+	return edits
+}
+
+// shortestEdits is the length of a shortest edit script, from the longest
+// common subsequence.
+func shortestEdits(a, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	return len(a) + len(b) - 2*lcs[0][0]
+}
+
+func TestDiffLinesIsShortest(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	text := func() []string {
+		lines := make([]string, rng.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.Intn(4)))
+		}
+		return lines
+	}
+	for i := 0; i < 500; i++ {
+		a, b := text(), text()
+		if got, want := apply(t, diffLines(a, b), a, b), shortestEdits(a, b); got != want {
+			t.Fatalf("diff of %q and %q: %d edits, want %d", a, b, got, want)
+		}
+	}
+}
+
+// TestDiffLinesBoundsCost diffs two maximum-size bodies with no line in
+// common, which a quadratic diff could not do in time or memory.
+func TestDiffLinesBoundsCost(t *testing.T) {
+	a := make([]string, 50000)
+	b := make([]string, 50000)
+	for i := range a {
+		a[i] = fmt.Sprintf("a%d", i)
+		b[i] = fmt.Sprintf("b%d", i)
+	}
+	if edits := apply(t, diffLines(a, b), a, b); edits != len(a)+len(b) {
+		t.Errorf("%d edits, want %d", edits, len(a)+len(b))
+	}
+
+	// A few changes in a large text still get the shortest script.
+	c := append([]string(nil), a...)
+	c[10], c[25000] = "changed", "changed"
+	c = append(c[:40000], c[40001:]...)
+	if edits := apply(t, diffLines(a, c), a, c); edits != 5 {
+		t.Errorf("%d edits, want 5", edits)
+	}
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"errors"
+	"strings"
+)
+
+// The general kinds of failure. Repositories and services wrap one of these,
+// or an error of that kind, so callers can tell failures apart with
+// errors.Is whatever backend produced them.
+var (
+	ErrNotFound        = errors.New("item not found")
+	ErrInvalid         = errors.New("item invalid")
+	ErrConflict        = errors.New("item conflicts with existing state")
+	ErrUnauthenticated = errors.New("authentication required")
+	ErrForbidden       = errors.New("operation not permitted")
+)
+
+// ErrVersionMismatch is the conflict of writing an item that was changed
+// since the version the writer read.
+var ErrVersionMismatch = newKindError(ErrConflict, "item was changed since it was read")
+
+// kindError is a specific failure that also matches the general kind it
+// belongs to, e.g. ErrEmailUnverified is an ErrForbidden.
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func newKindError(kind error, msg string) error {
+	return &kindError{kind, msg}
+}
+
+func (e *kindError) Error() string {
+	return e.msg
+}
+
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
+
+// FieldError describes why a single input field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is an ErrInvalid carrying the fields that failed.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// NewValidationError reports the given invalid fields.
+func NewValidationError(fields ...FieldError) error {
+	return &ValidationError{fields}
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+	if len(msgs) == 0 {
+		return ErrInvalid.Error()
+	}
+	return ErrInvalid.Error() + ": " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalid
+}
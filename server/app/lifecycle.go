@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	errs "github.com/pkg/errors"
+)
+
+// ArticleStatus is where an article is in its lifecycle. Authors move an
+// article between draft and in_review by editing it; publishing, unpublishing
+// and archiving are explicit transitions.
+type ArticleStatus string
+
+const (
+	StatusDraft     ArticleStatus = "draft"
+	StatusInReview  ArticleStatus = "in_review"
+	StatusPublished ArticleStatus = "published"
+	StatusArchived  ArticleStatus = "archived"
+)
+
+// ErrInvalidTransition is returned for a transition the article's current
+// status does not allow, such as archiving an archived article.
+var ErrInvalidTransition = newKindError(ErrConflict, "article status does not allow this transition")
+
+var errNotEditableStatus = NewValidationError(FieldError{Field: "status", Message: "can only be set to draft or in_review"})
+
+// editable reports whether authors may set s by creating or editing an
+// article.
+func (s ArticleStatus) editable() bool {
+	return s == StatusDraft || s == StatusInReview
+}
+
+// EffectiveStatus returns the article's status, treating articles stored
+// before statuses existed as published since they were already public.
+func (a Article) EffectiveStatus() ArticleStatus {
+	if a.Status == "" {
+		return StatusPublished
+	}
+	return a.Status
+}
+
+func (a *appService) PublishArticle(ctx context.Context, id string) (*Article, error) {
+	article, err := a.transition(ctx, id, PermEditAnyArticle, StatusPublished, StatusDraft, StatusInReview, StatusArchived)
+	return article, errs.Wrap(err, "service.Article.Publish")
+}
+
+func (a *appService) UnpublishArticle(ctx context.Context, id string) (*Article, error) {
+	article, err := a.transition(ctx, id, PermUnpublishAnyArticle, StatusDraft, StatusPublished)
+	return article, errs.Wrap(err, "service.Article.Unpublish")
+}
+
+func (a *appService) ArchiveArticle(ctx context.Context, id string) (*Article, error) {
+	article, err := a.transition(ctx, id, PermUnpublishAnyArticle, StatusArchived, StatusDraft, StatusInReview, StatusPublished)
+	return article, errs.Wrap(err, "service.Article.Archive")
+}
+
+// transition moves article id to status to when it is in one of from. Its
+// author may always do so; anyone else needs others.
+func (a *appService) transition(ctx context.Context, id string, others Permission, to ArticleStatus, from ...ArticleStatus) (*Article, error) {
+	article, err := a.appRepo.ReadArticle(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeOwned(ctx, article.AuthorID, PermWriteOwnArticles, others); err != nil {
+		return nil, err
+	}
+	if !hasStatus(from, article.EffectiveStatus()) {
+		return nil, ErrInvalidTransition
+	}
+	if to == StatusPublished {
+		if err := requireVerified(ctx); err != nil {
+			return nil, err
+		}
+	}
+	// Any explicit transition supersedes a pending schedule.
+	article.Status = to
+	article.PublishAt = 0
+	if to == StatusPublished {
+		article.PublishedAt = time.Now().UTC().Unix()
+	}
+	updated, err := a.appRepo.UpdateArticle(article)
+	if err != nil {
+		return nil, err
+	}
+	a.indexArticle(updated)
+	return updated, nil
+}
+
+// requireVerified fails unless the caller's email address is verified, which
+// publishing needs, whether now or on a schedule.
+func requireVerified(ctx context.Context) error {
+	actor, ok := AuthorFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+	if !actor.EmailVerified {
+		return ErrEmailUnverified
+	}
+	return nil
+}
+
+// ReadDrafts lists an author's unpublished drafts and articles in review.
+func (a *appService) ReadDrafts(ctx context.Context, authorID string, page PageRequest) (*ArticlePage, error) {
+	if err := authorizeOwned(ctx, authorID, PermWriteOwnArticles, PermEditAnyArticle); err != nil {
+		return nil, errs.Wrap(err, "service.Article.ReadDrafts")
+	}
+	page, err := page.normalize()
+	if err != nil {
+		return nil, errs.Wrap(err, "service.Article.ReadDrafts")
+	}
+	return a.appRepo.ReadArticles(ArticleQuery{
+		AuthorID: authorID,
+		Statuses: []ArticleStatus{StatusDraft, StatusInReview},
+	}, page)
+}
+
+func hasStatus(statuses []ArticleStatus, status ArticleStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
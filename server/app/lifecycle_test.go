@@ -0,0 +1,53 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"example.com/server/app"
+	"example.com/server/repository"
+)
+
+func TestUnverifiedAuthorWritesDraftsButCannotPublish(t *testing.T) {
+	store := repository.NewMemoryDB()
+	service := app.NewItemService(store, store, store, store)
+	author, err := store.CreateAuthor(&app.Author{FirstName: "Ada", Email: "ada@example.com", Role: app.DefaultRole})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := app.NewContext(context.Background(), author)
+
+	draft, err := service.CreateArticle(ctx, &app.Article{Title: "Notes", Body: "On the engine."})
+	if err != nil {
+		t.Fatalf("creating a draft: %v", err)
+	}
+	if _, err := service.PublishArticle(ctx, draft.Id); !errors.Is(err, app.ErrEmailUnverified) {
+		t.Errorf("publishing: got %v, want ErrEmailUnverified", err)
+	}
+	later := time.Now().Add(time.Hour).Unix()
+	if _, err := service.CreateArticle(ctx, &app.Article{Title: "Notes", Body: "On the engine.", PublishAt: later}); !errors.Is(err, app.ErrEmailUnverified) {
+		t.Errorf("creating a scheduled article: got %v, want ErrEmailUnverified", err)
+	}
+	update := &app.ArticleUpdate{Id: draft.Id, PublishAt: later, Version: draft.Version}
+	if _, err := service.UpdateArticle(ctx, update); !errors.Is(err, app.ErrEmailUnverified) {
+		t.Errorf("scheduling a draft: got %v, want ErrEmailUnverified", err)
+	}
+
+	author.EmailVerified = true
+	if author, err = store.UpdateAuthor(author); err != nil {
+		t.Fatal(err)
+	}
+	published, err := service.PublishArticle(app.NewContext(context.Background(), author), draft.Id)
+	if err != nil {
+		t.Fatalf("publishing once verified: %v", err)
+	}
+	hits, err := service.SearchArticles(ctx, "engine", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].Article.Version != published.Version {
+		t.Errorf("indexed %d hits, want the published article at version %d", len(hits), published.Version)
+	}
+}
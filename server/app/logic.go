@@ -1,80 +1,400 @@
 package app
 
 import (
+	"context"
+	"crypto/subtle"
 	"errors"
 	"time"
 
 	errs "github.com/pkg/errors"
-	"gopkg.in/dealancer/validate.v2"
+	"golang.org/x/crypto/bcrypt"
 )
 
 var (
-	ErrNotFound = errors.New("item not found")
-	ErrInvalid  = errors.New("item invalid")
+	ErrUnknownAuthor   = newKindError(ErrUnauthenticated, "unknown author")
+	ErrWrongPassword   = newKindError(ErrUnauthenticated, "wrong password")
+	ErrEmailUnverified = newKindError(ErrForbidden, "email address not verified")
 )
 
+var (
+	errUnknownRole          = NewValidationError(FieldError{Field: "role", Message: "unknown role"})
+	errNoCurrentPassword    = NewValidationError(FieldError{Field: "current_password", Message: "is required to change the password"})
+	errWrongCurrentPassword = NewValidationError(FieldError{Field: "current_password", Message: "is incorrect"})
+)
+
+// timingGuardHash is compared against when no author matches a login email so
+// that unknown and known emails take the same time to reject.
+const timingGuardHash = "$2a$10$gagySNX.Rr085uxVDJXTFe1mn/Ba0rpaAl1Rp27XpX2KquE7E2q9G"
+
 type appService struct {
-	appRepo AppRepository
+	appRepo      AppRepository
+	revisionRepo RevisionRepository
+	sessionRepo  SessionRepository
+	searchIndex  SearchIndex
 }
 
-func NewItemService(appRepo AppRepository) AppService {
+func NewItemService(appRepo AppRepository, revisionRepo RevisionRepository, sessionRepo SessionRepository, searchIndex SearchIndex) AppService {
 	return &appService{
 		appRepo,
+		revisionRepo,
+		sessionRepo,
+		searchIndex,
 	}
 }
 
 // Author service methods
-func (a *appService) CreateAuthor(author *Author) (*Author, error) {
-	if err := validate.Validate(author); err != nil {
-		return nil, errs.Wrap(ErrInvalid, "service.Author.Create")
+func (a *appService) CreateAuthor(ctx context.Context, author *Author) (*Author, error) {
+	if err := authorize(ctx, PermCreateAccount); err != nil {
+		return nil, errs.Wrap(err, "service.Author.Create")
+	}
+	if author.Role == "" {
+		author.Role = DefaultRole
+	}
+	if !author.Role.Valid() {
+		return nil, errs.Wrap(errUnknownRole, "service.Author.Create")
+	}
+	if author.Role != DefaultRole && !can(ctx, PermManageAuthors) {
+		return nil, errs.Wrap(ErrForbidden, "service.Author.Create")
+	}
+	author.EmailVerified = false
+	author.TOTPEnabled = false
+	if err := validateAuthor(author, true); err != nil {
+		return nil, errs.Wrap(err, "service.Author.Create")
+	}
+	if err := a.checkEmailFree(author.Email, ""); err != nil {
+		return nil, errs.Wrap(err, "service.Author.Create")
+	}
+	if err := hashPassword(author); err != nil {
+		return nil, err
 	}
 	// author.ID = uuid.New().String()
 	return a.appRepo.CreateAuthor(author)
 }
 
-// func (a *appService) LoginAuthor(email string) (*Author, error) {
-// 	// author.ID = uuid.New().String()
-// 	return a.appRepo.LoginAuthor(email)
-// }
+func (a *appService) LoginAuthor(ctx context.Context, email, password string) (*Author, error) {
+	author, err := a.appRepo.ReadAuthorByEmail(email)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			Author{Password: timingGuardHash}.CheckPasswordHarsh(password)
+			return nil, errs.Wrap(ErrUnknownAuthor, "service.Author.Login")
+		}
+		return nil, err
+	}
+	if !isPasswordHash(author.Password) {
+		return a.loginLegacyAuthor(author, password)
+	}
+	if !author.CheckPasswordHarsh(password) {
+		return nil, errs.Wrap(ErrWrongPassword, "service.Author.Login")
+	}
+	return author, nil
+}
+
+// loginLegacyAuthor checks a password stored in plaintext, as older DynamoDB
+// rows were, and replaces it with a hash once it matches.
+func (a *appService) loginLegacyAuthor(author *Author, password string) (*Author, error) {
+	if subtle.ConstantTimeCompare([]byte(author.Password), []byte(password)) != 1 {
+		return nil, errs.Wrap(ErrWrongPassword, "service.Author.Login")
+	}
+	if err := hashPassword(author); err != nil {
+		return nil, err
+	}
+	return a.appRepo.UpdateAuthor(author)
+}
+
+// hashPassword replaces the author's plaintext password with its bcrypt hash.
+func hashPassword(author *Author) error {
+	hashed, err := author.GenerateHashPassord()
+	if err != nil {
+		return errs.Wrap(err, "error hashing password")
+	}
+	author.Password = hashed
+	return nil
+}
 
-func (a *appService) ReadAuthor(id string) (*Author, error) {
+func isPasswordHash(password string) bool {
+	_, err := bcrypt.Cost([]byte(password))
+	return err == nil
+}
+
+// passwordMatches checks password against the author's, whether stored as a
+// hash or, for legacy rows, in plaintext.
+func passwordMatches(author *Author, password string) bool {
+	if !isPasswordHash(author.Password) {
+		return subtle.ConstantTimeCompare([]byte(author.Password), []byte(password)) == 1
+	}
+	return author.CheckPasswordHarsh(password)
+}
+
+func (a *appService) ReadAuthor(ctx context.Context, id string) (*Author, error) {
+	if err := authorize(ctx, PermReadAuthors); err != nil {
+		return nil, errs.Wrap(err, "service.Author.Read")
+	}
 	return a.appRepo.ReadAuthor(id)
 }
 
-func (a *appService) ReadAuthors() ([]*Author, error) {
-	return a.appRepo.ReadAuthors()
+func (a *appService) ReadAuthors(ctx context.Context, page PageRequest) (*AuthorPage, error) {
+	if err := authorize(ctx, PermReadAuthors); err != nil {
+		return nil, errs.Wrap(err, "service.Author.Read")
+	}
+	page, err := page.normalize()
+	if err != nil {
+		return nil, errs.Wrap(err, "service.Author.Read")
+	}
+	return a.appRepo.ReadAuthors(page)
 }
 
-func (a *appService) UpdateAuthor(author *Author) (*Author, error) {
-	return a.appRepo.UpdateAuthor(author)
+// UpdateAuthor changes the fields set on author. Authors changing their own
+// password must give currentPassword; the change logs out their other
+// sessions.
+func (a *appService) UpdateAuthor(ctx context.Context, author *Author, currentPassword string) (*Author, error) {
+	if err := authorizeOwned(ctx, author.Id, PermManageOwnAccount, PermManageAuthors); err != nil {
+		return nil, errs.Wrap(err, "service.Author.Update")
+	}
+	existing, err := a.appRepo.ReadAuthor(author.Id)
+	if err != nil {
+		return nil, err
+	}
+	if author.Version != existing.Version {
+		return nil, errs.Wrap(ErrVersionMismatch, "service.Author.Update")
+	}
+	if author.Role == "" {
+		author.Role = existing.EffectiveRole()
+	}
+	if !author.Role.Valid() {
+		return nil, errs.Wrap(errUnknownRole, "service.Author.Update")
+	}
+	if author.Role != existing.EffectiveRole() && !can(ctx, PermManageAuthors) {
+		return nil, errs.Wrap(ErrForbidden, "service.Author.Update")
+	}
+	if author.Email != "" && author.Email != existing.Email {
+		if err := a.checkEmailFree(author.Email, author.Id); err != nil {
+			return nil, errs.Wrap(err, "service.Author.Update")
+		}
+	}
+	newPassword := author.Password != ""
+	if actor, ok := AuthorFromContext(ctx); ok && newPassword && actor.Id == author.Id {
+		if currentPassword == "" {
+			return nil, errs.Wrap(errNoCurrentPassword, "service.Author.Update")
+		}
+		if !passwordMatches(existing, currentPassword) {
+			return nil, errs.Wrap(errWrongCurrentPassword, "service.Author.Update")
+		}
+	}
+	mergeAuthor(author, existing)
+	if err := validateAuthor(author, newPassword); err != nil {
+		return nil, errs.Wrap(err, "service.Author.Update")
+	}
+	if !newPassword {
+		return a.appRepo.UpdateAuthor(author)
+	}
+	if err := hashPassword(author); err != nil {
+		return nil, err
+	}
+	updated, err := a.appRepo.UpdateAuthor(author)
+	if err != nil {
+		return nil, err
+	}
+	if err := revokeSessions(a.sessionRepo, updated.Id, sessionFromContext(ctx)); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// checkEmailFree fails with ErrConflict when email belongs to an author other
+// than id.
+func (a *appService) checkEmailFree(email, id string) error {
+	owner, err := a.appRepo.ReadAuthorByEmail(email)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if owner.Id != id {
+		return errs.Wrap(ErrConflict, "email already registered")
+	}
+	return nil
+}
+
+// mergeAuthor fills the fields an update left empty from the stored author,
+// so repositories can write the whole record. Verification is carried over
+// unless the email changed, and two-factor and identity links are never
+// changed here.
+func mergeAuthor(author, existing *Author) {
+	if author.FirstName == "" {
+		author.FirstName = existing.FirstName
+	}
+	if author.LastName == "" {
+		author.LastName = existing.LastName
+	}
+	if author.Password == "" {
+		author.Password = existing.Password
+	}
+	if author.Email == "" {
+		author.Email = existing.Email
+	}
+	author.EmailVerified = existing.EmailVerified && author.Email == existing.Email
+	author.TOTPEnabled = existing.TOTPEnabled
+	author.TOTPSecret = existing.TOTPSecret
+	author.TOTPRecoveryCodes = existing.TOTPRecoveryCodes
+	author.TOTPLastStep = existing.TOTPLastStep
+	author.IdentityIssuer = existing.IdentityIssuer
+	author.IdentitySubject = existing.IdentitySubject
 }
 
-func (a *appService) DeleteAuthor(id string) error {
+func (a *appService) DeleteAuthor(ctx context.Context, id string) error {
+	if err := authorizeOwned(ctx, id, PermManageOwnAccount, PermManageAuthors); err != nil {
+		return errs.Wrap(err, "service.Author.Delete")
+	}
 	return a.appRepo.DeleteAuthor(id)
 }
 
 // Article service methods
-func (a *appService) CreateArticle(article *Article) (*Article, error) {
-	if err := validate.Validate(article); err != nil {
-		return nil, errs.Wrap(ErrInvalid, "service.Article.Create")
+func (a *appService) CreateArticle(ctx context.Context, article *Article) (*Article, error) {
+	if err := authorize(ctx, PermWriteOwnArticles); err != nil {
+		return nil, errs.Wrap(err, "service.Article.Create")
+	}
+	actor, _ := AuthorFromContext(ctx)
+	if article.Status == "" {
+		article.Status = StatusDraft
+	}
+	if !article.Status.editable() {
+		return nil, errs.Wrap(errNotEditableStatus, "service.Article.Create")
+	}
+	if err := validateFields(article); err != nil {
+		return nil, errs.Wrap(err, "service.Article.Create")
+	}
+	now := time.Now().UTC()
+	if article.PublishAt < 0 {
+		article.PublishAt = 0
 	}
+	if article.PublishAt != 0 {
+		if err := validatePublishAt(article, now); err != nil {
+			return nil, errs.Wrap(err, "service.Article.Create")
+		}
+		if err := requireVerified(ctx); err != nil {
+			return nil, errs.Wrap(err, "service.Article.Create")
+		}
+	}
+	article.PublishedAt = 0
+	article.AuthorID = actor.Id
 	// article.ID = uuid.New().String()
-	article.CreateAt = time.Now().UTC().Unix()
-	return a.appRepo.CreateArticle(article)
+	article.CreateAt = now.Unix()
+	article, err := a.appRepo.CreateArticle(article)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := a.revisionRepo.CreateRevision(newRevision(ctx, article, 1)); err != nil {
+		return nil, err
+	}
+	a.indexArticle(article)
+	return article, nil
 }
 
-func (a *appService) ReadArticle(id string) (*Article, error) {
-	return a.appRepo.ReadArticle(id)
+func (a *appService) ReadArticle(ctx context.Context, id string) (*Article, error) {
+	if err := authorize(ctx, PermReadArticles); err != nil {
+		return nil, errs.Wrap(err, "service.Article.Read")
+	}
+	article, err := a.appRepo.ReadArticle(id)
+	if err != nil {
+		return nil, err
+	}
+	// Unpublished articles are hidden, not forbidden, from other readers.
+	if article.EffectiveStatus() != StatusPublished &&
+		authorizeOwned(ctx, article.AuthorID, PermWriteOwnArticles, PermEditAnyArticle) != nil {
+		return nil, errs.Wrap(ErrNotFound, "service.Article.Read")
+	}
+	return article, nil
 }
 
-func (a *appService) ReadArticles() ([]*Article, error) {
-	return a.appRepo.ReadArticles()
+func (a *appService) ReadArticles(ctx context.Context, query ArticleQuery, page PageRequest) (*ArticlePage, error) {
+	if err := authorize(ctx, PermReadArticles); err != nil {
+		return nil, errs.Wrap(err, "service.Article.Read")
+	}
+	if err := query.validate(); err != nil {
+		return nil, errs.Wrap(err, "service.Article.Read")
+	}
+	page, err := page.normalize()
+	if err != nil {
+		return nil, errs.Wrap(err, "service.Article.Read")
+	}
+	query.Statuses = []ArticleStatus{StatusPublished}
+	return a.appRepo.ReadArticles(query, page)
 }
 
-func (a *appService) UpdateArticle(article *Article) (*Article, error) {
-	return a.appRepo.UpdateArticle(article)
+func (a *appService) UpdateArticle(ctx context.Context, update *ArticleUpdate) (*Article, error) {
+	existing, err := a.appRepo.ReadArticle(update.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeOwned(ctx, existing.AuthorID, PermWriteOwnArticles, PermEditAnyArticle); err != nil {
+		return nil, errs.Wrap(err, "service.Article.Update")
+	}
+	if update.Version != existing.Version {
+		return nil, errs.Wrap(ErrVersionMismatch, "service.Article.Update")
+	}
+	if update.Status != "" && update.Status != existing.EffectiveStatus() &&
+		!(update.Status.editable() && existing.EffectiveStatus().editable()) {
+		return nil, errs.Wrap(errNotEditableStatus, "service.Article.Update")
+	}
+	article := mergeArticle(update, existing)
+	if err := validateFields(article); err != nil {
+		return nil, errs.Wrap(err, "service.Article.Update")
+	}
+	if update.PublishAt > 0 {
+		if err := validatePublishAt(article, time.Now().UTC()); err != nil {
+			return nil, errs.Wrap(err, "service.Article.Update")
+		}
+		if err := requireVerified(ctx); err != nil {
+			return nil, errs.Wrap(err, "service.Article.Update")
+		}
+	}
+	return a.saveArticle(ctx, article, existing)
 }
 
-func (a *appService) DeleteArticle(id string) error {
-	return a.appRepo.DeleteArticle(id)
+// mergeArticle applies update to the stored article. Ownership, creation
+// and publication time never change.
+func mergeArticle(update *ArticleUpdate, existing *Article) *Article {
+	article := *existing
+	article.Status = existing.EffectiveStatus()
+	if update.Title != "" {
+		article.Title = update.Title
+	}
+	if update.Body != "" {
+		article.Body = update.Body
+	}
+	if update.Author != "" {
+		article.Author = update.Author
+	}
+	if update.Rate != nil {
+		article.Rate = *update.Rate
+	}
+	if update.Status != "" {
+		article.Status = update.Status
+	}
+	switch {
+	case update.PublishAt > 0:
+		article.PublishAt = update.PublishAt
+	case update.PublishAt < 0:
+		article.PublishAt = 0
+	}
+	return &article
+}
+
+// DeleteArticle removes an article and its history. Editors can take other
+// authors' articles down by unpublishing or archiving them, but only admins
+// can delete them.
+func (a *appService) DeleteArticle(ctx context.Context, id string) error {
+	existing, err := a.appRepo.ReadArticle(id)
+	if err != nil {
+		return err
+	}
+	if err := authorizeOwned(ctx, existing.AuthorID, PermWriteOwnArticles, PermDeleteAnyArticle); err != nil {
+		return errs.Wrap(err, "service.Article.Delete")
+	}
+	if err := a.appRepo.DeleteArticle(id); err != nil {
+		return err
+	}
+	a.unindexArticle(id)
+	return a.revisionRepo.DeleteRevisions(id)
 }
@@ -1,25 +1,127 @@
 package app
 
 import (
+	"time"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
 type Author struct {
-	Id        string    `json:"id" gorm:"primarykey"`
-	FirstName string    `json:"firstname"`
-	LastName  string    `json:"lastname"`
-	Email     string    `json:"email"`
-	Password  string    `json:"password"`
-	Articles  []Article `json:"articles"`
+	Id            string `json:"id" gorm:"primarykey"`
+	FirstName     string `json:"firstname" validate:"lte=100" message:"must be at most 100 bytes"`
+	LastName      string `json:"lastname" validate:"lte=100" message:"must be at most 100 bytes"`
+	Email         string `json:"email" validate:"format=email & lte=254" message:"must be a valid email address"`
+	Role          Role   `json:"role"`
+	EmailVerified bool   `json:"email_verified"`
+	TOTPEnabled   bool   `json:"totp_enabled"`
+	// Credentials and identity links are never serialized to clients; the
+	// dynamodbav tags keep them stored in DynamoDB. Password strength is
+	// checked before hashing, in validateAuthor.
+	Password          string    `json:"-" dynamodbav:"password"`
+	TOTPSecret        string    `json:"-" dynamodbav:"totp_secret"`
+	TOTPRecoveryCodes string    `json:"-" dynamodbav:"totp_recovery_codes"`
+	TOTPLastStep      int64     `json:"-" dynamodbav:"totp_last_step"`
+	IdentityIssuer    string    `json:"-" dynamodbav:"identity_issuer"`
+	IdentitySubject   string    `json:"-" dynamodbav:"identity_subject"`
+	Articles          []Article `json:"articles"`
+	// Version counts writes, starting at 1; see AppRepository.
+	Version int64 `json:"version"`
 }
 
 type Article struct {
 	Id       string `json:"id" gorm:"primarykey"`
 	AuthorID string `json:"author_id"`
-	Title    string `json:"title"`
-	Body     string `json:"body"`
-	Author   string `json:"author"`
-	Rate     int    `json:"rate"`
+	Title    string `json:"title" validate:"gte=1 & lte=200" message:"must be between 1 and 200 bytes"`
+	Body     string `json:"body" validate:"gte=1 & lte=100000" message:"must be between 1 and 100000 bytes"`
+	Author   string `json:"author" validate:"lte=100" message:"must be at most 100 bytes"`
+	Rate     int    `json:"rate" validate:"gte=0 & lte=5" message:"must be between 0 and 5"`
+	CreateAt int64  `json:"created_at"`
+	// Status moves through the lifecycle in lifecycle.go; PublishedAt is
+	// when the article was last published. PublishAt, when set on a draft
+	// or an article in review, is when the scheduler will publish it; a
+	// negative value in an update cancels the schedule.
+	Status      ArticleStatus `json:"status"`
+	PublishedAt int64         `json:"published_at"`
+	PublishAt   int64         `json:"publish_at"`
+	// Version counts writes, starting at 1; see AppRepository.
+	Version int64 `json:"version"`
+}
+
+// ArticleUpdate is an edit to the article Id at Version. Empty strings and a
+// nil Rate leave their field unchanged, as does a zero PublishAt; a negative
+// PublishAt cancels the schedule.
+type ArticleUpdate struct {
+	Id        string        `json:"-"`
+	Title     string        `json:"title"`
+	Body      string        `json:"body"`
+	Author    string        `json:"author"`
+	Rate      *int          `json:"rate"`
+	Status    ArticleStatus `json:"status"`
+	PublishAt int64         `json:"publish_at"`
+	Version   int64         `json:"-"`
+}
+
+// Revision is an immutable snapshot of an article's content, saved when the
+// article is created and on every edit. Numbers count up from 1 per article.
+type Revision struct {
+	ArticleID string `json:"article_id" gorm:"primary_key"`
+	Number    int    `json:"number" gorm:"primary_key;auto_increment:false"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	Author    string `json:"author"`
+	Rate      int    `json:"rate"`
+	EditorID  string `json:"editor_id"`
+	CreateAt  int64  `json:"created_at"`
+}
+
+// Session is one login of an author. Every refresh rotates the token stored
+// on the session, so a session is the family of all tokens it has issued.
+type Session struct {
+	Id         string `json:"id" gorm:"primarykey"`
+	AuthorID   string `json:"author_id"`
+	TokenHash  string `json:"-" dynamodbav:"token_hash"`
+	UserAgent  string `json:"user_agent"`
+	IP         string `json:"ip"`
+	CreateAt   int64  `json:"created_at"`
+	LastUsedAt int64  `json:"last_used_at"`
+	ExpiresAt  int64  `json:"expires_at"`
+	RevokedAt  int64  `json:"revoked_at"`
+}
+
+// Active reports whether the session can still be used at now.
+func (s Session) Active(now time.Time) bool {
+	return s.RevokedAt == 0 && now.Unix() < s.ExpiresAt
+}
+
+// APIKey is a named, scoped credential for programmatic access. Only a hash
+// of the secret part is stored.
+type APIKey struct {
+	Id         string `json:"id" gorm:"primarykey"`
+	AuthorID   string `json:"author_id"`
+	Name       string `json:"name"`
+	Scopes     string `json:"scopes"`
+	KeyHash    string `json:"-" dynamodbav:"key_hash"`
+	CreateAt   int64  `json:"created_at"`
+	LastUsedAt int64  `json:"last_used_at"`
+	RevokedAt  int64  `json:"revoked_at"`
+}
+
+// LoginAttempts tracks recent failed logins for one throttling key, such as
+// a client IP or an account.
+type LoginAttempts struct {
+	Key         string `json:"key" gorm:"primary_key"`
+	Failures    int    `json:"failures"`
+	LastFailure int64  `json:"last_failure"`
+	LockedUntil int64  `json:"locked_until"`
+}
+
+// AuditEntry records a security relevant event.
+type AuditEntry struct {
+	Id       string `json:"id" gorm:"primarykey"`
+	Event    string `json:"event"`
+	Subject  string `json:"subject"`
+	IP       string `json:"ip"`
+	Reason   string `json:"reason"`
 	CreateAt int64  `json:"created_at"`
 }
 
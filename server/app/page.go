@@ -0,0 +1,44 @@
+package app
+
+// Page sizes for listings that do not ask for one, and the most any request
+// may ask for.
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// PageRequest asks for up to Limit items following the position Cursor
+// points at. An empty Cursor starts at the beginning; cursors are opaque and
+// only meaningful to the repository that issued them.
+type PageRequest struct {
+	Limit  int
+	Cursor string
+}
+
+// AuthorPage is one page of authors. NextCursor is empty on the last page.
+type AuthorPage struct {
+	Authors    []*Author
+	NextCursor string
+}
+
+// ArticlePage is one page of articles. NextCursor is empty on the last page.
+type ArticlePage struct {
+	Articles   []*Article
+	NextCursor string
+}
+
+// normalize applies the default limit and rejects limits out of range, so
+// repositories always see 1 <= Limit <= MaxPageLimit.
+func (p PageRequest) normalize() (PageRequest, error) {
+	if p.Limit == 0 {
+		p.Limit = DefaultPageLimit
+	}
+	if p.Limit < 1 || p.Limit > MaxPageLimit {
+		return p, NewValidationError(FieldError{Field: "limit", Message: "must be between 1 and 100"})
+	}
+	return p, nil
+}
+
+// ErrInvalidCursor is returned by repositories for a cursor they did not
+// issue.
+var ErrInvalidCursor = NewValidationError(FieldError{Field: "cursor", Message: "is not a valid cursor"})
@@ -0,0 +1,134 @@
+package app
+
+import "context"
+
+// Role is the access level of an author.
+type Role string
+
+const (
+	RoleReader Role = "reader"
+	RoleAuthor Role = "author"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+// DefaultRole is given to signups and to stored authors without a role.
+const DefaultRole = RoleAuthor
+
+// Permission is a single operation an actor may be allowed to perform.
+type Permission int
+
+const (
+	PermReadArticles Permission = iota
+	PermReadAuthors
+	PermCreateAccount
+	PermManageOwnAccount
+	PermWriteOwnArticles
+	PermEditAnyArticle
+	PermUnpublishAnyArticle
+	PermDeleteAnyArticle
+	PermManageAuthors
+)
+
+// guestPermissions apply to requests without an authenticated author.
+var guestPermissions = []Permission{
+	PermReadArticles,
+	PermReadAuthors,
+	PermCreateAccount,
+}
+
+var rolePermissions = map[Role][]Permission{
+	RoleReader: {
+		PermReadArticles,
+		PermReadAuthors,
+		PermManageOwnAccount,
+	},
+	RoleAuthor: {
+		PermReadArticles,
+		PermReadAuthors,
+		PermManageOwnAccount,
+		PermWriteOwnArticles,
+	},
+	RoleEditor: {
+		PermReadArticles,
+		PermReadAuthors,
+		PermManageOwnAccount,
+		PermWriteOwnArticles,
+		PermEditAnyArticle,
+		PermUnpublishAnyArticle,
+	},
+	RoleAdmin: {
+		PermReadArticles,
+		PermReadAuthors,
+		PermCreateAccount,
+		PermManageOwnAccount,
+		PermWriteOwnArticles,
+		PermEditAnyArticle,
+		PermUnpublishAnyArticle,
+		PermDeleteAnyArticle,
+		PermManageAuthors,
+	},
+}
+
+// Valid reports whether r is one of the known roles.
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Can reports whether the role grants p.
+func (r Role) Can(p Permission) bool {
+	return hasPermission(rolePermissions[r], p)
+}
+
+// EffectiveRole returns the author's role, treating a missing role as
+// DefaultRole so rows written before roles existed keep working.
+func (a Author) EffectiveRole() Role {
+	if a.Role == "" {
+		return DefaultRole
+	}
+	return a.Role
+}
+
+func hasPermission(perms []Permission, p Permission) bool {
+	for _, perm := range perms {
+		if perm == p {
+			return true
+		}
+	}
+	return false
+}
+
+// can reports whether the actor in ctx, or a guest when there is none, holds
+// p. Scoped requests additionally need a scope granting p.
+func can(ctx context.Context, p Permission) bool {
+	actor, ok := AuthorFromContext(ctx)
+	if !ok {
+		return hasPermission(guestPermissions, p)
+	}
+	if scopes, ok := scopesFromContext(ctx); ok && !scopesAllow(scopes, p) {
+		return false
+	}
+	return actor.EffectiveRole().Can(p)
+}
+
+// authorize fails with ErrUnauthenticated for guests and ErrForbidden for
+// authors when the actor in ctx does not hold p.
+func authorize(ctx context.Context, p Permission) error {
+	if can(ctx, p) {
+		return nil
+	}
+	if _, ok := AuthorFromContext(ctx); !ok {
+		return ErrUnauthenticated
+	}
+	return ErrForbidden
+}
+
+// authorizeOwned checks own when the actor in ctx is ownerID and others
+// otherwise.
+func authorizeOwned(ctx context.Context, ownerID string, own, others Permission) error {
+	if actor, ok := AuthorFromContext(ctx); ok && actor.Id == ownerID && can(ctx, own) {
+		return nil
+	}
+	return authorize(ctx, others)
+}
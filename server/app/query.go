@@ -0,0 +1,85 @@
+package app
+
+import (
+	"strings"
+)
+
+// Fields an article listing can be sorted on, named as in JSON.
+const (
+	SortCreatedAt = "created_at"
+	SortRate      = "rate"
+)
+
+// SortKey orders a listing by one field.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// ArticleQuery narrows and orders an article listing. Zero values do not
+// filter. Statuses matches any of the given statuses. Since and Until bound
+// the creation time in unix seconds, Since inclusive and Until exclusive. An
+// empty Sort leaves the order to the repository, newest first where it can.
+type ArticleQuery struct {
+	AuthorID string
+	Statuses []ArticleStatus
+	MinRate  int
+	Since    int64
+	Until    int64
+	Sort     []SortKey
+}
+
+// ErrUnsupportedQuery is returned by repositories for an article query they
+// could only answer by scanning every article.
+var ErrUnsupportedQuery = newKindError(ErrInvalid, "article query not supported")
+
+// ParseSort reads a sort spec such as "-created_at,rate", where a leading
+// minus sorts that field descending.
+func ParseSort(spec string) ([]SortKey, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	var keys []SortKey
+	seen := map[string]bool{}
+	for _, part := range strings.Split(spec, ",") {
+		key := SortKey{Field: strings.TrimSpace(part)}
+		if strings.HasPrefix(key.Field, "-") {
+			key.Field, key.Desc = key.Field[1:], true
+		}
+		if key.Field != SortCreatedAt && key.Field != SortRate {
+			return nil, NewValidationError(FieldError{Field: "sort", Message: "can only use created_at and rate"})
+		}
+		if seen[key.Field] {
+			return nil, NewValidationError(FieldError{Field: "sort", Message: "names " + key.Field + " twice"})
+		}
+		seen[key.Field] = true
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// SortSpec is the inverse of ParseSort.
+func SortSpec(keys []SortKey) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key.Field
+		if key.Desc {
+			parts[i] = "-" + key.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func (q ArticleQuery) validate() error {
+	var fields []FieldError
+	if q.MinRate < 0 || q.MinRate > 5 {
+		fields = append(fields, FieldError{Field: "min_rate", Message: "must be between 0 and 5"})
+	}
+	if q.Since != 0 && q.Until != 0 && q.Since >= q.Until {
+		fields = append(fields, FieldError{Field: "until", Message: "must be after since"})
+	}
+	if len(fields) > 0 {
+		return NewValidationError(fields...)
+	}
+	return nil
+}
@@ -1,14 +1,80 @@
 package app
 
+// AppRepository stores authors and articles. Creating one sets its Version
+// to 1. Updates are compare-and-set: the stored item must still be at the
+// Version passed in, which is then incremented, or the update fails with
+// ErrVersionMismatch. Items stored before versions existed are at version 0.
 type AppRepository interface {
 	CreateAuthor(author *Author) (*Author, error)
 	ReadAuthor(id string) (*Author, error)
-	ReadAuthors() ([]*Author, error)
+	ReadAuthorByEmail(email string) (*Author, error)
+	ReadAuthors(page PageRequest) (*AuthorPage, error)
 	UpdateAuthor(author *Author) (*Author, error)
 	DeleteAuthor(id string) error
 	CreateArticle(Article *Article) (*Article, error)
 	ReadArticle(id string) (*Article, error)
-	ReadArticles() ([]*Article, error)
+	ReadArticles(query ArticleQuery, page PageRequest) (*ArticlePage, error)
 	UpdateArticle(Article *Article) (*Article, error)
 	DeleteArticle(id string) error
 }
+
+// RevisionRepository stores article revisions. CreateRevision fails with
+// ErrConflict when the article already has a revision with that number, and
+// ReadRevisions lists an article's revisions oldest first.
+// UpdateArticleWithRevision is AppRepository.UpdateArticle that also creates
+// revision, in one atomic write: unless both succeed, neither is stored.
+type RevisionRepository interface {
+	CreateRevision(revision *Revision) (*Revision, error)
+	UpdateArticleWithRevision(article *Article, revision *Revision) (*Article, error)
+	ReadRevision(articleID string, number int) (*Revision, error)
+	ReadRevisions(articleID string) ([]*Revision, error)
+	DeleteRevisions(articleID string) error
+}
+
+// ScheduleRepository finds and publishes scheduled articles. ReadDueArticles
+// returns up to limit drafts and articles in review with a publish_at at or
+// before now. PublishScheduledArticle publishes article id only if it is
+// still unpublished and scheduled for publishAt, in a single conditional
+// write, and otherwise fails with ErrConflict; that is what keeps replicas
+// polling together from publishing an article twice.
+type ScheduleRepository interface {
+	ReadDueArticles(now int64, limit int) ([]*Article, error)
+	PublishScheduledArticle(id string, publishAt, publishedAt int64) (*Article, error)
+}
+
+// SessionRepository stores sessions. RotateSession writes session's new
+// TokenHash and LastUsedAt only if the stored session is unrevoked and still
+// has previousHash, in a single conditional write, and otherwise fails with
+// ErrConflict; that is what lets only one of two concurrent refreshes with
+// the same token succeed.
+type SessionRepository interface {
+	CreateSession(session *Session) (*Session, error)
+	ReadSession(id string) (*Session, error)
+	ReadSessions(authorID string) ([]*Session, error)
+	UpdateSession(session *Session) (*Session, error)
+	RotateSession(session *Session, previousHash string) (*Session, error)
+}
+
+// APIKeyRepository stores API keys. TouchAPIKey sets only a key's last use,
+// and only while it is not revoked, so a revocation made meanwhile is never
+// written over; it fails with ErrNotFound when the key is missing or revoked.
+type APIKeyRepository interface {
+	CreateAPIKey(key *APIKey) (*APIKey, error)
+	ReadAPIKey(id string) (*APIKey, error)
+	ReadAPIKeys(authorID string) ([]*APIKey, error)
+	UpdateAPIKey(key *APIKey) (*APIKey, error)
+	TouchAPIKey(id string, usedAt int64) error
+}
+
+// AttemptRepository stores login throttling state. ReadAttempts returns an
+// empty LoginAttempts for keys without failures. RecordFailure counts a
+// failure of key at now and returns the key's new state, in a single atomic
+// write so that concurrent failures are all counted: failures from before
+// since are forgotten first, and a key that is not locked when it reaches
+// threshold failures is locked until lockUntil.
+type AttemptRepository interface {
+	ReadAttempts(key string) (*LoginAttempts, error)
+	RecordFailure(key string, now, since int64, threshold int, lockUntil int64) (*LoginAttempts, error)
+	DeleteAttempts(key string) error
+	CreateAuditEntry(entry *AuditEntry) error
+}
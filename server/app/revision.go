@@ -0,0 +1,130 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	errs "github.com/pkg/errors"
+)
+
+// newRevision snapshots article's content as revision number, made by the
+// author in ctx.
+func newRevision(ctx context.Context, article *Article, number int) *Revision {
+	actor, _ := AuthorFromContext(ctx)
+	return &Revision{
+		ArticleID: article.Id,
+		Number:    number,
+		Title:     article.Title,
+		Body:      article.Body,
+		Author:    article.Author,
+		Rate:      article.Rate,
+		EditorID:  actor.Id,
+		CreateAt:  time.Now().UTC().Unix(),
+	}
+}
+
+// saveArticle writes article and records its content as the next revision
+// after those of existing, together, so an edit that loses to a concurrent
+// one fails with ErrVersionMismatch and leaves no trace in the history.
+// Articles from before revisions existed get their stored content saved as
+// revision 1 first.
+func (a *appService) saveArticle(ctx context.Context, article, existing *Article) (*Article, error) {
+	revisions, err := a.revisionRepo.ReadRevisions(article.Id)
+	if err != nil {
+		return nil, err
+	}
+	var next int
+	if len(revisions) > 0 {
+		next = revisions[len(revisions)-1].Number + 1
+	} else {
+		original := newRevision(ctx, existing, 1)
+		original.EditorID = existing.AuthorID
+		original.CreateAt = existing.CreateAt
+		// A concurrent edit may have saved the same original already.
+		if _, err := a.revisionRepo.CreateRevision(original); err != nil && !errors.Is(err, ErrConflict) {
+			return nil, err
+		}
+		next = 2
+	}
+	updated, err := a.revisionRepo.UpdateArticleWithRevision(article, newRevision(ctx, article, next))
+	if err != nil {
+		return nil, err
+	}
+	a.indexArticle(updated)
+	return updated, nil
+}
+
+// readOwnArticle reads article id for its author or an editor.
+func (a *appService) readOwnArticle(ctx context.Context, id string) (*Article, error) {
+	article, err := a.appRepo.ReadArticle(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeOwned(ctx, article.AuthorID, PermWriteOwnArticles, PermEditAnyArticle); err != nil {
+		return nil, err
+	}
+	return article, nil
+}
+
+func (a *appService) ReadRevisions(ctx context.Context, articleID string) ([]*Revision, error) {
+	if _, err := a.readOwnArticle(ctx, articleID); err != nil {
+		return nil, errs.Wrap(err, "service.Revision.Read")
+	}
+	return a.revisionRepo.ReadRevisions(articleID)
+}
+
+func (a *appService) ReadRevision(ctx context.Context, articleID string, number int) (*Revision, error) {
+	if _, err := a.readOwnArticle(ctx, articleID); err != nil {
+		return nil, errs.Wrap(err, "service.Revision.Read")
+	}
+	return a.revisionRepo.ReadRevision(articleID, number)
+}
+
+// DiffRevisions returns a unified diff from revision from to revision to of
+// an article, covering its title, author, rate and body.
+func (a *appService) DiffRevisions(ctx context.Context, articleID string, from, to int) (string, error) {
+	if _, err := a.readOwnArticle(ctx, articleID); err != nil {
+		return "", errs.Wrap(err, "service.Revision.Diff")
+	}
+	older, err := a.revisionRepo.ReadRevision(articleID, from)
+	if err != nil {
+		return "", err
+	}
+	newer, err := a.revisionRepo.ReadRevision(articleID, to)
+	if err != nil {
+		return "", err
+	}
+	return unifiedDiff(
+		fmt.Sprintf("revision %d", from),
+		fmt.Sprintf("revision %d", to),
+		older.document(),
+		newer.document(),
+	), nil
+}
+
+// document is the text revisions are diffed as.
+func (r Revision) document() string {
+	return fmt.Sprintf("Title: %s\nAuthor: %s\nRate: %d\n\n%s", r.Title, r.Author, r.Rate, r.Body)
+}
+
+// RestoreRevision makes an earlier revision's content current again. The
+// restore is itself an edit, so it is saved as a new revision and history
+// is never rewritten.
+func (a *appService) RestoreRevision(ctx context.Context, articleID string, number int) (*Article, error) {
+	existing, err := a.readOwnArticle(ctx, articleID)
+	if err != nil {
+		return nil, errs.Wrap(err, "service.Revision.Restore")
+	}
+	revision, err := a.revisionRepo.ReadRevision(articleID, number)
+	if err != nil {
+		return nil, err
+	}
+	article := *existing
+	article.Title = revision.Title
+	article.Body = revision.Body
+	article.Author = revision.Author
+	article.Rate = revision.Rate
+	return a.saveArticle(ctx, &article, existing)
+}
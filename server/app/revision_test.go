@@ -0,0 +1,56 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"example.com/server/app"
+	"example.com/server/repository"
+)
+
+// newAuthorContext stores a verified author and returns a context acting as
+// them.
+func newAuthorContext(t *testing.T, store *repository.MemoryDB, email string) context.Context {
+	t.Helper()
+	author, err := store.CreateAuthor(&app.Author{FirstName: "Ada", Email: email, Role: app.DefaultRole, EmailVerified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app.NewContext(context.Background(), author)
+}
+
+func TestDiffRevisions(t *testing.T) {
+	store := repository.NewMemoryDB()
+	service := app.NewItemService(store, store, store, store)
+	ctx := newAuthorContext(t, store, "ada@example.com")
+	article, err := service.CreateArticle(ctx, &app.Article{Title: "Notes", Body: "On the engine.\nIt computes.\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	update := &app.ArticleUpdate{Id: article.Id, Body: "On the engine.\nIt weaves.\n", Version: article.Version}
+	if _, err := service.UpdateArticle(ctx, update); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := service.DiffRevisions(ctx, article.Id, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "--- revision 1\n+++ revision 2\n@@ -3,4 +3,4 @@\n Rate: 0\n \n On the engine.\n-It computes.\n+It weaves.\n"
+	if diff != want {
+		t.Errorf("got\n%s\nwant\n%s", diff, want)
+	}
+
+	other := newAuthorContext(t, store, "grace@example.com")
+	if _, err := service.DiffRevisions(other, article.Id, 1, 2); !errors.Is(err, app.ErrForbidden) {
+		t.Errorf("another author's diff: got %v, want ErrForbidden", err)
+	}
+	if _, err := service.DiffRevisions(ctx, article.Id, 1, 3); !errors.Is(err, app.ErrNotFound) {
+		t.Errorf("diff to a missing revision: got %v, want ErrNotFound", err)
+	}
+	if diff, _ := service.DiffRevisions(ctx, article.Id, 2, 2); strings.TrimSpace(diff) != "" {
+		t.Errorf("diff of a revision with itself: %q", diff)
+	}
+}
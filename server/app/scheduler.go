@@ -0,0 +1,120 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	errs "github.com/pkg/errors"
+)
+
+// DefaultPublishInterval is how often the scheduler looks for due articles
+// when no interval is configured.
+const DefaultPublishInterval = 30 * time.Second
+
+// scheduleBatch bounds how many due articles one pass reads at a time.
+const scheduleBatch = 100
+
+var errPublishAtPast = NewValidationError(FieldError{Field: "publish_at", Message: "must be in the future"})
+
+var errPublishAtStatus = NewValidationError(FieldError{Field: "publish_at", Message: "can only be set on draft or in_review articles"})
+
+// Clock is the scheduler's source of time, so it can be driven by a fake in
+// tests.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now().UTC() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// SystemClock is the wall clock.
+var SystemClock Clock = systemClock{}
+
+type scheduler struct {
+	scheduleRepo ScheduleRepository
+	searchIndex  SearchIndex
+	clock        Clock
+	interval     time.Duration
+}
+
+func NewScheduler(scheduleRepo ScheduleRepository, searchIndex SearchIndex, clock Clock, interval time.Duration) Scheduler {
+	if interval <= 0 {
+		interval = DefaultPublishInterval
+	}
+	return &scheduler{
+		scheduleRepo,
+		searchIndex,
+		clock,
+		interval,
+	}
+}
+
+// Run publishes due articles every interval until ctx is done. A pass in
+// progress when ctx is cancelled stops after the article it is publishing.
+func (s *scheduler) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.clock.After(s.interval):
+		}
+		if _, err := s.PublishDue(ctx); err != nil {
+			log.Printf("publishing scheduled articles: %v", err)
+		}
+	}
+}
+
+// PublishDue publishes every draft or article in review whose publish_at has
+// passed and returns how many this replica published. Articles another
+// replica got to first, or that were edited since they were read, are
+// skipped.
+func (s *scheduler) PublishDue(ctx context.Context) (int, error) {
+	now := s.clock.Now().Unix()
+	published := 0
+	for {
+		due, err := s.scheduleRepo.ReadDueArticles(now, scheduleBatch)
+		if err != nil {
+			return published, errs.Wrap(err, "service.Scheduler.PublishDue")
+		}
+		claimed := 0
+		for _, article := range due {
+			if ctx.Err() != nil {
+				return published, nil
+			}
+			updated, err := s.scheduleRepo.PublishScheduledArticle(article.Id, article.PublishAt, now)
+			if errors.Is(err, ErrConflict) {
+				continue
+			}
+			if err != nil {
+				return published, errs.Wrap(err, "service.Scheduler.PublishDue")
+			}
+			claimed++
+			if err := s.searchIndex.IndexArticle(updated); err != nil {
+				log.Printf("indexing article %s: %v", updated.Id, err)
+			}
+		}
+		published += claimed
+		// A short batch was everything due; a full one where nothing could be
+		// claimed would only be read again.
+		if len(due) < scheduleBatch || claimed == 0 {
+			return published, nil
+		}
+	}
+}
+
+// validatePublishAt checks a schedule an author set by creating or editing
+// article.
+func validatePublishAt(article *Article, now time.Time) error {
+	if !article.EffectiveStatus().editable() {
+		return errPublishAtStatus
+	}
+	if article.PublishAt <= now.Unix() {
+		return errPublishAtPast
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	errs "github.com/pkg/errors"
+)
+
+// fakeClock stands still at now. Each After call is announced on waits, and
+// its channel fires when the test sends on ticks.
+type fakeClock struct {
+	now   time.Time
+	waits chan time.Duration
+	ticks chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now, waits: make(chan time.Duration), ticks: make(chan time.Time)}
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.waits <- d
+	return c.ticks
+}
+
+// fakeSchedule holds scheduled articles. Articles in taken are published by
+// another replica the moment this one tries to, as a lost race would be.
+type fakeSchedule struct {
+	mu        sync.Mutex
+	articles  map[string]*Article
+	taken     map[string]bool
+	reads     int
+	published []string
+}
+
+func newFakeSchedule() *fakeSchedule {
+	return &fakeSchedule{articles: map[string]*Article{}, taken: map[string]bool{}}
+}
+
+func (r *fakeSchedule) schedule(n int, publishAt int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("article-%d", len(r.articles))
+		r.articles[id] = &Article{Id: id, Status: StatusDraft, PublishAt: publishAt}
+	}
+}
+
+func (r *fakeSchedule) ReadDueArticles(now int64, limit int) ([]*Article, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reads++
+	due := []*Article{}
+	for _, article := range r.articles {
+		if article.Status == StatusDraft && article.PublishAt > 0 && article.PublishAt <= now {
+			copied := *article
+			due = append(due, &copied)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].Id < due[j].Id })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+func (r *fakeSchedule) PublishScheduledArticle(id string, publishAt, publishedAt int64) (*Article, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	article := r.articles[id]
+	if article.Status != StatusDraft || article.PublishAt != publishAt {
+		return nil, errs.Wrap(ErrConflict, id)
+	}
+	article.Status = StatusPublished
+	article.PublishedAt = publishedAt
+	article.PublishAt = 0
+	if r.taken[id] {
+		return nil, errs.Wrap(ErrConflict, id)
+	}
+	r.published = append(r.published, id)
+	copied := *article
+	return &copied, nil
+}
+
+func (r *fakeSchedule) publishedCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.published)
+}
+
+// fakeIndex records what the scheduler indexes.
+type fakeIndex struct {
+	mu      sync.Mutex
+	indexed map[string]bool
+}
+
+func (idx *fakeIndex) IndexArticle(article *Article) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.indexed[article.Id] = true
+	return nil
+}
+
+func (idx *fakeIndex) RemoveArticle(id string) error { return nil }
+
+func (idx *fakeIndex) SearchArticles(query string, limit int) ([]*SearchHit, error) {
+	return nil, nil
+}
+
+func TestSchedulerRunPublishesEveryInterval(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := newFakeClock(now)
+	repo := newFakeSchedule()
+	s := NewScheduler(repo, &fakeIndex{indexed: map[string]bool{}}, clock, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	for tick := 1; tick <= 3; tick++ {
+		if d := <-clock.waits; d != time.Minute {
+			t.Fatalf("waited %s, want the 1m interval", d)
+		}
+		if published := repo.publishedCount(); published != tick-1 {
+			t.Fatalf("published %d articles before tick %d, want %d", published, tick, tick-1)
+		}
+		repo.schedule(1, now.Unix()-1)
+		clock.ticks <- now
+	}
+	<-clock.waits
+	if published := repo.publishedCount(); published != 3 {
+		t.Errorf("published %d articles in 3 ticks, want 3", published)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after its context was cancelled")
+	}
+}
+
+func TestPublishDueSkipsConflicts(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	repo := newFakeSchedule()
+	repo.schedule(3, now.Unix()-1)
+	repo.schedule(1, now.Unix()+60)
+	repo.taken["article-1"] = true
+	index := &fakeIndex{indexed: map[string]bool{}}
+	s := NewScheduler(repo, index, newFakeClock(now), time.Minute)
+
+	published, err := s.PublishDue(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published != 2 {
+		t.Errorf("published %d, want 2", published)
+	}
+	for _, id := range []string{"article-0", "article-2"} {
+		if !index.indexed[id] {
+			t.Errorf("%s was not indexed", id)
+		}
+	}
+	if index.indexed["article-1"] || index.indexed["article-3"] {
+		t.Errorf("indexed %v, want only the articles this replica published", index.indexed)
+	}
+}
+
+func TestPublishDuePagesPastFullBatches(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	repo := newFakeSchedule()
+	repo.schedule(2*scheduleBatch+10, now.Unix()-1)
+	repo.taken["article-5"] = true
+	s := NewScheduler(repo, &fakeIndex{indexed: map[string]bool{}}, newFakeClock(now), time.Minute)
+
+	published, err := s.PublishDue(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 2*scheduleBatch + 9; published != want {
+		t.Errorf("published %d, want %d", published, want)
+	}
+	if repo.reads != 3 {
+		t.Errorf("read %d batches, want 3", repo.reads)
+	}
+}
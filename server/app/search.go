@@ -0,0 +1,84 @@
+package app
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	errs "github.com/pkg/errors"
+)
+
+// SearchHit is an article matching a search. Snippet is an HTML-escaped
+// excerpt of the article with the matched words wrapped in <mark>.
+type SearchHit struct {
+	Article *Article `json:"article"`
+	Score   float64  `json:"score"`
+	Snippet string   `json:"snippet"`
+}
+
+// SearchIndex keeps articles searchable by title and body. Search returns at
+// most limit hits, best first, for articles containing every word of query.
+type SearchIndex interface {
+	IndexArticle(article *Article) error
+	RemoveArticle(id string) error
+	SearchArticles(query string, limit int) ([]*SearchHit, error)
+}
+
+func (a *appService) SearchArticles(ctx context.Context, query string, limit int) ([]*SearchHit, error) {
+	if err := authorize(ctx, PermReadArticles); err != nil {
+		return nil, errs.Wrap(err, "service.Article.Search")
+	}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errs.Wrap(NewValidationError(FieldError{Field: "q", Message: "must not be empty"}), "service.Article.Search")
+	}
+	page, err := PageRequest{Limit: limit}.normalize()
+	if err != nil {
+		return nil, errs.Wrap(err, "service.Article.Search")
+	}
+	return a.searchIndex.SearchArticles(query, page.Limit)
+}
+
+// indexArticle and unindexArticle run after the article itself has been
+// written, so a failure only leaves search stale and is logged rather than
+// failing the request.
+// Only published articles are indexed.
+func (a *appService) indexArticle(article *Article) {
+	if article.EffectiveStatus() != StatusPublished {
+		a.unindexArticle(article.Id)
+		return
+	}
+	if err := a.searchIndex.IndexArticle(article); err != nil {
+		log.Printf("indexing article %s: %v", article.Id, err)
+	}
+}
+
+func (a *appService) unindexArticle(id string) {
+	if err := a.searchIndex.RemoveArticle(id); err != nil {
+		log.Printf("removing article %s from search: %v", id, err)
+	}
+}
+
+// RebuildIndex adds every published article to index, for indexes that do
+// not persist across restarts.
+func RebuildIndex(appRepo AppRepository, index SearchIndex) error {
+	page := PageRequest{Limit: MaxPageLimit}
+	for {
+		articles, err := appRepo.ReadArticles(ArticleQuery{}, page)
+		if err != nil {
+			return err
+		}
+		for _, article := range articles.Articles {
+			if article.EffectiveStatus() != StatusPublished {
+				continue
+			}
+			if err := index.IndexArticle(article); err != nil {
+				return err
+			}
+		}
+		if articles.NextCursor == "" {
+			return nil
+		}
+		page.Cursor = articles.NextCursor
+	}
+}
@@ -0,0 +1,64 @@
+package app_test
+
+import (
+	"testing"
+
+	"example.com/server/app"
+	"example.com/server/repository"
+)
+
+func TestSearchArticles(t *testing.T) {
+	store := repository.NewMemoryDB()
+	service := app.NewItemService(store, store, store, store)
+	ctx := newAuthorContext(t, store, "ada@example.com")
+	create := func(title, body string, publish bool) *app.Article {
+		t.Helper()
+		article, err := service.CreateArticle(ctx, &app.Article{Title: title, Body: body})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if publish {
+			if article, err = service.PublishArticle(ctx, article.Id); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return article
+	}
+	search := func(query string) []string {
+		t.Helper()
+		hits, err := service.SearchArticles(ctx, query, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]string, len(hits))
+		for i, hit := range hits {
+			ids[i] = hit.Article.Id
+		}
+		return ids
+	}
+
+	inTitle := create("The engine", "Notes on a machine.", true)
+	inBody := create("Notes", "The machine has an engine and a mill, and a store of numbers besides.", true)
+	create("Looms", "Punched cards drive the loom.", true)
+	draft := create("Engine draft", "An engine, unpublished.", false)
+
+	if got := search("engine"); len(got) != 2 || got[0] != inTitle.Id || got[1] != inBody.Id {
+		t.Errorf("engine: got %v, want the title match and then the body match", got)
+	}
+	if got := search("engine mill"); len(got) != 1 || got[0] != inBody.Id {
+		t.Errorf("engine mill: got %v, want only the article with both words", got)
+	}
+	if got := search("difference"); len(got) != 0 {
+		t.Errorf("difference: got %v, want no hits", got)
+	}
+
+	if _, err := service.UnpublishArticle(ctx, inTitle.Id); err != nil {
+		t.Fatal(err)
+	}
+	if got := search("engine"); len(got) != 1 || got[0] != inBody.Id {
+		t.Errorf("after unpublishing: got %v, want only %s", got, inBody.Id)
+	}
+	if got := search("unpublished"); len(got) != 0 {
+		t.Errorf("got %v, want the draft %s left out", got, draft.Id)
+	}
+}
@@ -1,14 +1,69 @@
 package app
 
+import (
+	"context"
+	"time"
+)
+
 type AppService interface {
-	CreateAuthor(author *Author) (*Author, error)
-	ReadAuthor(id string) (*Author, error)
-	ReadAuthors() ([]*Author, error)
-	UpdateAuthor(author *Author) (*Author, error)
-	DeleteAuthor(id string) error
-	CreateArticle(Article *Article) (*Article, error)
-	ReadArticle(id string) (*Article, error)
-	ReadArticles() ([]*Article, error)
-	UpdateArticle(Article *Article) (*Article, error)
-	DeleteArticle(id string) error
+	CreateAuthor(ctx context.Context, author *Author) (*Author, error)
+	LoginAuthor(ctx context.Context, email, password string) (*Author, error)
+	ReadAuthor(ctx context.Context, id string) (*Author, error)
+	ReadAuthors(ctx context.Context, page PageRequest) (*AuthorPage, error)
+	UpdateAuthor(ctx context.Context, author *Author, currentPassword string) (*Author, error)
+	DeleteAuthor(ctx context.Context, id string) error
+	CreateArticle(ctx context.Context, Article *Article) (*Article, error)
+	ReadArticle(ctx context.Context, id string) (*Article, error)
+	ReadArticles(ctx context.Context, query ArticleQuery, page PageRequest) (*ArticlePage, error)
+	UpdateArticle(ctx context.Context, update *ArticleUpdate) (*Article, error)
+	DeleteArticle(ctx context.Context, id string) error
+	SearchArticles(ctx context.Context, query string, limit int) ([]*SearchHit, error)
+	PublishArticle(ctx context.Context, id string) (*Article, error)
+	UnpublishArticle(ctx context.Context, id string) (*Article, error)
+	ArchiveArticle(ctx context.Context, id string) (*Article, error)
+	ReadDrafts(ctx context.Context, authorID string, page PageRequest) (*ArticlePage, error)
+	ReadRevisions(ctx context.Context, articleID string) ([]*Revision, error)
+	ReadRevision(ctx context.Context, articleID string, number int) (*Revision, error)
+	DiffRevisions(ctx context.Context, articleID string, from, to int) (string, error)
+	RestoreRevision(ctx context.Context, articleID string, number int) (*Article, error)
+}
+
+type SessionService interface {
+	StartSession(ctx context.Context, author *Author, userAgent, ip string) (*Session, string, error)
+	RefreshSession(ctx context.Context, refreshToken string) (*Session, string, error)
+	ValidateSession(ctx context.Context, id, authorID string) (*Session, error)
+	ReadSessions(ctx context.Context) ([]*Session, error)
+	RevokeSession(ctx context.Context, id string) error
+}
+
+type AccountService interface {
+	SendVerification(ctx context.Context, author *Author) error
+	VerifyEmail(ctx context.Context, token string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+	EnrollTOTP(ctx context.Context, authorID string) (*TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, authorID, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, authorID, code string) error
+	VerifySecondFactor(ctx context.Context, authorID, code string) (*Author, error)
+	LinkIdentity(ctx context.Context, identity ExternalIdentity) (*Author, error)
+}
+
+type KeyService interface {
+	CreateAPIKey(ctx context.Context, authorID, name string, scopes []Scope) (*APIKey, string, error)
+	ReadAPIKeys(ctx context.Context, authorID string) ([]*APIKey, error)
+	RevokeAPIKey(ctx context.Context, authorID, id string) error
+	AuthenticateAPIKey(ctx context.Context, key string) (*APIKey, error)
+}
+
+// Scheduler publishes articles whose publish_at has come. Run blocks until
+// its context is done; PublishDue makes a single pass.
+type Scheduler interface {
+	Run(ctx context.Context)
+	PublishDue(ctx context.Context) (int, error)
+}
+
+type LoginThrottle interface {
+	Check(ctx context.Context, ip, subject string) (time.Duration, error)
+	Fail(ctx context.Context, ip, subject, reason string) error
+	Succeed(ctx context.Context, ip, subject string) error
 }
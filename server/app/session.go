@@ -0,0 +1,178 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	errs "github.com/pkg/errors"
+)
+
+// SessionTTL bounds how long a login can be kept alive by refreshing.
+const SessionTTL = 30 * 24 * time.Hour
+
+var (
+	ErrInvalidToken = newKindError(ErrUnauthenticated, "invalid or expired token")
+	ErrTokenReused  = newKindError(ErrUnauthenticated, "refresh token reused")
+)
+
+type sessionService struct {
+	sessionRepo SessionRepository
+}
+
+func NewSessionService(sessionRepo SessionRepository) SessionService {
+	return &sessionService{
+		sessionRepo,
+	}
+}
+
+func (s *sessionService) StartSession(ctx context.Context, author *Author, userAgent, ip string) (*Session, string, error) {
+	secret, err := newTokenSecret()
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now().UTC()
+	session := &Session{
+		Id:         uuid.New().String(),
+		AuthorID:   author.Id,
+		TokenHash:  hashToken(secret),
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreateAt:   now.Unix(),
+		LastUsedAt: now.Unix(),
+		ExpiresAt:  now.Add(SessionTTL).Unix(),
+	}
+	session, err = s.sessionRepo.CreateSession(session)
+	if err != nil {
+		return nil, "", err
+	}
+	return session, session.Id + "." + secret, nil
+}
+
+func (s *sessionService) RefreshSession(ctx context.Context, refreshToken string) (*Session, string, error) {
+	id, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok {
+		return nil, "", errs.Wrap(ErrInvalidToken, "service.Session.Refresh")
+	}
+	session, err := s.sessionRepo.ReadSession(id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, "", errs.Wrap(ErrInvalidToken, "service.Session.Refresh")
+		}
+		return nil, "", err
+	}
+	now := time.Now().UTC()
+	if !session.Active(now) {
+		return nil, "", errs.Wrap(ErrInvalidToken, "service.Session.Refresh")
+	}
+	previous := hashToken(secret)
+	if subtle.ConstantTimeCompare([]byte(session.TokenHash), []byte(previous)) != 1 {
+		// A well-formed token for a live session that is not the current one
+		// has already been rotated away, so the family is compromised.
+		return nil, "", s.revokeReused(session, now)
+	}
+	next, err := newTokenSecret()
+	if err != nil {
+		return nil, "", err
+	}
+	session.TokenHash = hashToken(next)
+	session.LastUsedAt = now.Unix()
+	rotated, err := s.sessionRepo.RotateSession(session, previous)
+	if errors.Is(err, ErrConflict) {
+		// Another refresh with the same token rotated it first, which is
+		// reuse all the same.
+		return nil, "", s.revokeReused(session, now)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return rotated, rotated.Id + "." + next, nil
+}
+
+// revokeReused revokes a session whose refresh token was used twice and
+// returns ErrTokenReused.
+func (s *sessionService) revokeReused(session *Session, now time.Time) error {
+	stored, err := s.sessionRepo.ReadSession(session.Id)
+	if err != nil {
+		return err
+	}
+	if stored.RevokedAt == 0 {
+		stored.RevokedAt = now.Unix()
+		if _, err := s.sessionRepo.UpdateSession(stored); err != nil {
+			return err
+		}
+	}
+	return errs.Wrap(ErrTokenReused, "service.Session.Refresh")
+}
+
+func (s *sessionService) ValidateSession(ctx context.Context, id, authorID string) (*Session, error) {
+	session, err := s.sessionRepo.ReadSession(id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, errs.Wrap(ErrInvalidToken, "service.Session.Validate")
+		}
+		return nil, err
+	}
+	if session.AuthorID != authorID || !session.Active(time.Now().UTC()) {
+		return nil, errs.Wrap(ErrInvalidToken, "service.Session.Validate")
+	}
+	return session, nil
+}
+
+func (s *sessionService) ReadSessions(ctx context.Context) ([]*Session, error) {
+	if err := authorize(ctx, PermManageOwnAccount); err != nil {
+		return nil, errs.Wrap(err, "service.Session.Read")
+	}
+	actor, _ := AuthorFromContext(ctx)
+	sessions, err := s.sessionRepo.ReadSessions(actor.Id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	active := []*Session{}
+	for _, session := range sessions {
+		if session.Active(now) {
+			active = append(active, session)
+		}
+	}
+	return active, nil
+}
+
+func (s *sessionService) RevokeSession(ctx context.Context, id string) error {
+	session, err := s.sessionRepo.ReadSession(id)
+	if err != nil {
+		return err
+	}
+	if err := authorizeOwned(ctx, session.AuthorID, PermManageOwnAccount, PermManageAuthors); err != nil {
+		return errs.Wrap(err, "service.Session.Revoke")
+	}
+	if session.RevokedAt != 0 {
+		return nil
+	}
+	session.RevokedAt = time.Now().UTC().Unix()
+	_, err = s.sessionRepo.UpdateSession(session)
+	return err
+}
+
+// newTokenSecret returns 256 random bits encoded for use in a token.
+func newTokenSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken is how token secrets are stored; they are random enough that an
+// unsalted SHA-256 is sufficient.
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,133 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/server/app"
+	"example.com/server/repository"
+)
+
+func startSession(t *testing.T, store *repository.MemoryDB, sessions app.SessionService) (*app.Session, string) {
+	t.Helper()
+	ctx := newAuthorContext(t, store, "ada@example.com")
+	author, _ := app.AuthorFromContext(ctx)
+	session, token, err := sessions.StartSession(ctx, author, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return session, token
+}
+
+func TestRefreshSessionRotatesToken(t *testing.T) {
+	store := repository.NewMemoryDB()
+	sessions := app.NewSessionService(store)
+	session, first := startSession(t, store, sessions)
+
+	refreshed, second, err := sessions.RefreshSession(context.Background(), first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.Id != session.Id || second == first {
+		t.Fatalf("refresh gave session %s and token %q", refreshed.Id, second)
+	}
+	_, third, err := sessions.RefreshSession(context.Background(), second)
+	if err != nil {
+		t.Fatalf("refreshing with the rotated token: %v", err)
+	}
+	if _, err := sessions.ValidateSession(context.Background(), session.Id, session.AuthorID); err != nil {
+		t.Errorf("validating the refreshed session: %v", err)
+	}
+
+	for _, token := range []string{"", "no-dot", "missing.secret", session.Id} {
+		if _, _, err := sessions.RefreshSession(context.Background(), token); !errors.Is(err, app.ErrInvalidToken) {
+			t.Errorf("refreshing with %q: got %v, want ErrInvalidToken", token, err)
+		}
+	}
+	if _, _, err := sessions.RefreshSession(context.Background(), third); err != nil {
+		t.Errorf("malformed tokens revoked the session: %v", err)
+	}
+}
+
+func TestRefreshSessionRevokesFamilyOnReuse(t *testing.T) {
+	store := repository.NewMemoryDB()
+	sessions := app.NewSessionService(store)
+	session, first := startSession(t, store, sessions)
+	_, second, err := sessions.RefreshSession(context.Background(), first)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := sessions.RefreshSession(context.Background(), first); !errors.Is(err, app.ErrTokenReused) {
+		t.Fatalf("reusing a rotated token: got %v, want ErrTokenReused", err)
+	}
+	if _, _, err := sessions.RefreshSession(context.Background(), second); !errors.Is(err, app.ErrInvalidToken) {
+		t.Errorf("refreshing after reuse: got %v, want ErrInvalidToken", err)
+	}
+	if _, err := sessions.ValidateSession(context.Background(), session.Id, session.AuthorID); !errors.Is(err, app.ErrInvalidToken) {
+		t.Errorf("validating after reuse: got %v, want ErrInvalidToken", err)
+	}
+	stored, err := store.ReadSession(session.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.RevokedAt == 0 {
+		t.Error("session not revoked after reuse")
+	}
+}
+
+func TestRefreshSessionConcurrentReuse(t *testing.T) {
+	store := repository.NewMemoryDB()
+	sessions := app.NewSessionService(store)
+	session, token := startSession(t, store, sessions)
+
+	const refreshes = 8
+	var wg sync.WaitGroup
+	errc := make(chan error, refreshes)
+	for i := 0; i < refreshes; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := sessions.RefreshSession(context.Background(), token)
+			errc <- err
+		}()
+	}
+	wg.Wait()
+	close(errc)
+
+	succeeded := 0
+	for err := range errc {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, app.ErrUnauthenticated):
+			t.Errorf("concurrent refresh: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d of %d concurrent refreshes succeeded, want 1", succeeded, refreshes)
+	}
+	stored, err := store.ReadSession(session.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.RevokedAt == 0 {
+		t.Error("session not revoked after concurrent reuse")
+	}
+}
+
+func TestRefreshSessionRejectsExpired(t *testing.T) {
+	store := repository.NewMemoryDB()
+	sessions := app.NewSessionService(store)
+	session, token := startSession(t, store, sessions)
+	session.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	if _, err := store.UpdateSession(session); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := sessions.RefreshSession(context.Background(), token); !errors.Is(err, app.ErrInvalidToken) {
+		t.Errorf("refreshing an expired session: got %v, want ErrInvalidToken", err)
+	}
+}
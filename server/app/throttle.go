@@ -0,0 +1,148 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ThrottlePolicy describes how failed logins for one key are slowed down.
+// After FreeAttempts failures each further attempt must wait BaseDelay,
+// doubling up to MaxDelay; LockoutThreshold failures lock the key for
+// LockoutDuration. Failures older than Window are forgotten.
+type ThrottlePolicy struct {
+	FreeAttempts     int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	Window           time.Duration
+}
+
+var (
+	// DefaultAccountPolicy throttles guessing against a single account.
+	DefaultAccountPolicy = ThrottlePolicy{
+		FreeAttempts:     3,
+		BaseDelay:        time.Second,
+		MaxDelay:         5 * time.Minute,
+		LockoutThreshold: 10,
+		LockoutDuration:  15 * time.Minute,
+		Window:           time.Hour,
+	}
+	// DefaultIPPolicy is looser since many users can share an address.
+	DefaultIPPolicy = ThrottlePolicy{
+		FreeAttempts:     10,
+		BaseDelay:        time.Second,
+		MaxDelay:         5 * time.Minute,
+		LockoutThreshold: 50,
+		LockoutDuration:  15 * time.Minute,
+		Window:           time.Hour,
+	}
+)
+
+// wait returns how long the key must wait before its next attempt at now.
+func (p ThrottlePolicy) wait(a *LoginAttempts, now time.Time) time.Duration {
+	if a.Failures == 0 || now.Sub(time.Unix(a.LastFailure, 0)) > p.Window {
+		return 0
+	}
+	if locked := time.Unix(a.LockedUntil, 0); now.Before(locked) {
+		return locked.Sub(now)
+	}
+	if a.Failures < p.FreeAttempts {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := p.FreeAttempts; i < a.Failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if next := time.Unix(a.LastFailure, 0).Add(delay); now.Before(next) {
+		return next.Sub(now)
+	}
+	return 0
+}
+
+type loginThrottle struct {
+	attemptRepo   AttemptRepository
+	accountPolicy ThrottlePolicy
+	ipPolicy      ThrottlePolicy
+}
+
+func NewLoginThrottle(attemptRepo AttemptRepository, accountPolicy, ipPolicy ThrottlePolicy) LoginThrottle {
+	return &loginThrottle{
+		attemptRepo,
+		accountPolicy,
+		ipPolicy,
+	}
+}
+
+// Check returns how long the caller must wait before trying to log in to
+// subject from ip; zero means the attempt may proceed.
+func (t *loginThrottle) Check(ctx context.Context, ip, subject string) (time.Duration, error) {
+	now := time.Now().UTC()
+	var longest time.Duration
+	for _, k := range t.keys(ip, subject) {
+		attempts, err := t.attemptRepo.ReadAttempts(k.key)
+		if err != nil {
+			return 0, err
+		}
+		if wait := k.policy.wait(attempts, now); wait > longest {
+			longest = wait
+		}
+	}
+	return longest, nil
+}
+
+func (t *loginThrottle) Fail(ctx context.Context, ip, subject, reason string) error {
+	now := time.Now().UTC()
+	for _, k := range t.keys(ip, subject) {
+		lockUntil := now.Add(k.policy.LockoutDuration).Unix()
+		attempts, err := t.attemptRepo.RecordFailure(k.key, now.Unix(), now.Add(-k.policy.Window).Unix(), k.policy.LockoutThreshold, lockUntil)
+		if err != nil {
+			return err
+		}
+		if attempts.LockedUntil == lockUntil {
+			if err := t.audit("login_locked", subject, ip, k.key, now); err != nil {
+				return err
+			}
+		}
+	}
+	return t.audit("login_failed", subject, ip, reason, now)
+}
+
+// Succeed clears the account's failures. The IP's failures are kept so that
+// one valid login cannot reset a spray across many accounts.
+func (t *loginThrottle) Succeed(ctx context.Context, ip, subject string) error {
+	return t.attemptRepo.DeleteAttempts(accountKey(subject))
+}
+
+func (t *loginThrottle) audit(event, subject, ip, reason string, now time.Time) error {
+	return t.attemptRepo.CreateAuditEntry(&AuditEntry{
+		Id:       uuid.New().String(),
+		Event:    event,
+		Subject:  subject,
+		IP:       ip,
+		Reason:   reason,
+		CreateAt: now.Unix(),
+	})
+}
+
+type throttleKey struct {
+	key    string
+	policy ThrottlePolicy
+}
+
+func (t *loginThrottle) keys(ip, subject string) []throttleKey {
+	return []throttleKey{
+		{"ip:" + ip, t.ipPolicy},
+		{accountKey(subject), t.accountPolicy},
+	}
+}
+
+func accountKey(subject string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(subject))
+}
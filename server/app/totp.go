@@ -0,0 +1,209 @@
+package app
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	errs "github.com/pkg/errors"
+)
+
+// TOTP parameters follow the RFC 6238 defaults that authenticator apps expect.
+const (
+	totpIssuer   = "Modart"
+	totpDigits   = 6
+	totpPeriod   = 30
+	totpSkew     = 1
+	recoveryKeys = 10
+)
+
+var (
+	ErrTOTPEnabled     = newKindError(ErrConflict, "two-factor authentication already enabled")
+	ErrTOTPNotEnrolled = newKindError(ErrConflict, "two-factor authentication not enrolled")
+	ErrInvalidCode     = newKindError(ErrInvalid, "invalid authentication code")
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTPEnrollment is handed to the author to add to an authenticator app.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URL    string `json:"url"`
+}
+
+func (s *accountService) EnrollTOTP(ctx context.Context, authorID string) (*TOTPEnrollment, error) {
+	author, err := s.ownAccount(ctx, authorID)
+	if err != nil {
+		return nil, errs.Wrap(err, "service.Account.EnrollTOTP")
+	}
+	if author.TOTPEnabled {
+		return nil, errs.Wrap(ErrTOTPEnabled, "service.Account.EnrollTOTP")
+	}
+	key := make([]byte, 20)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	author.TOTPSecret = totpEncoding.EncodeToString(key)
+	author.TOTPRecoveryCodes = ""
+	if _, err := s.appRepo.UpdateAuthor(author); err != nil {
+		return nil, err
+	}
+	label := url.PathEscape(totpIssuer + ":" + author.Email)
+	params := url.Values{}
+	params.Set("secret", author.TOTPSecret)
+	params.Set("issuer", totpIssuer)
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(totpPeriod))
+	return &TOTPEnrollment{
+		Secret: author.TOTPSecret,
+		URL:    "otpauth://totp/" + label + "?" + params.Encode(),
+	}, nil
+}
+
+func (s *accountService) ConfirmTOTP(ctx context.Context, authorID, code string) ([]string, error) {
+	author, err := s.ownAccount(ctx, authorID)
+	if err != nil {
+		return nil, errs.Wrap(err, "service.Account.ConfirmTOTP")
+	}
+	if author.TOTPEnabled {
+		return nil, errs.Wrap(ErrTOTPEnabled, "service.Account.ConfirmTOTP")
+	}
+	if author.TOTPSecret == "" {
+		return nil, errs.Wrap(ErrTOTPNotEnrolled, "service.Account.ConfirmTOTP")
+	}
+	if !checkTOTP(author, code, time.Now()) {
+		return nil, errs.Wrap(ErrInvalidCode, "service.Account.ConfirmTOTP")
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	author.TOTPEnabled = true
+	author.TOTPRecoveryCodes = strings.Join(hashes, ",")
+	if _, err := s.appRepo.UpdateAuthor(author); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *accountService) DisableTOTP(ctx context.Context, authorID, code string) error {
+	author, err := s.ownAccount(ctx, authorID)
+	if err != nil {
+		return errs.Wrap(err, "service.Account.DisableTOTP")
+	}
+	if !author.TOTPEnabled {
+		return errs.Wrap(ErrTOTPNotEnrolled, "service.Account.DisableTOTP")
+	}
+	if !checkTOTP(author, code, time.Now()) && !useRecoveryCode(author, code) {
+		return errs.Wrap(ErrInvalidCode, "service.Account.DisableTOTP")
+	}
+	author.TOTPEnabled = false
+	author.TOTPSecret = ""
+	author.TOTPRecoveryCodes = ""
+	author.TOTPLastStep = 0
+	_, err = s.appRepo.UpdateAuthor(author)
+	return err
+}
+
+func (s *accountService) VerifySecondFactor(ctx context.Context, authorID, code string) (*Author, error) {
+	author, err := s.appRepo.ReadAuthor(authorID)
+	if err != nil {
+		return nil, err
+	}
+	if !author.TOTPEnabled {
+		return nil, errs.Wrap(ErrTOTPNotEnrolled, "service.Account.VerifySecondFactor")
+	}
+	if !checkTOTP(author, code, time.Now()) && !useRecoveryCode(author, code) {
+		return nil, errs.Wrap(ErrInvalidCode, "service.Account.VerifySecondFactor")
+	}
+	// Persist the consumed step or recovery code so neither can be replayed.
+	if _, err := s.appRepo.UpdateAuthor(author); err != nil {
+		return nil, err
+	}
+	return author, nil
+}
+
+// ownAccount loads authorID, allowing only that author to act on it.
+func (s *accountService) ownAccount(ctx context.Context, authorID string) (*Author, error) {
+	actor, ok := AuthorFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+	if actor.Id != authorID || !can(ctx, PermManageOwnAccount) {
+		return nil, ErrForbidden
+	}
+	return s.appRepo.ReadAuthor(authorID)
+}
+
+// checkTOTP accepts a code from the current step or its neighbours, and
+// records the matched step on author so a code is only accepted once.
+func checkTOTP(author *Author, code string, now time.Time) bool {
+	key, err := totpEncoding.DecodeString(author.TOTPSecret)
+	if err != nil || len(code) != totpDigits {
+		return false
+	}
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= author.TOTPLastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			author.TOTPLastStep = step
+			return true
+		}
+	}
+	return false
+}
+
+// totpCode is the RFC 4226 HOTP value for counter step.
+func totpCode(key []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// newRecoveryCodes returns fresh one-time codes and the hashes to store.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryKeys)
+	hashes := make([]string, recoveryKeys)
+	for i := range codes {
+		buf := make([]byte, 6)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(totpEncoding.EncodeToString(buf))
+		codes[i] = raw[:5] + "-" + raw[5:10]
+		hashes[i] = hashToken(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// useRecoveryCode removes code from the author's remaining recovery codes.
+func useRecoveryCode(author *Author, code string) bool {
+	if author.TOTPRecoveryCodes == "" {
+		return false
+	}
+	hashed := hashToken(strings.ToLower(strings.TrimSpace(code)))
+	hashes := strings.Split(author.TOTPRecoveryCodes, ",")
+	for i, h := range hashes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(hashed)) == 1 {
+			hashes = append(hashes[:i], hashes[i+1:]...)
+			author.TOTPRecoveryCodes = strings.Join(hashes, ",")
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	errs "github.com/pkg/errors"
+)
+
+// fakeAuthors stores authors for the account service; its other repository
+// methods are not used.
+type fakeAuthors struct {
+	AppRepository
+	authors map[string]Author
+}
+
+func (r *fakeAuthors) ReadAuthor(id string) (*Author, error) {
+	author, ok := r.authors[id]
+	if !ok {
+		return nil, errs.Wrap(ErrNotFound, id)
+	}
+	return &author, nil
+}
+
+func (r *fakeAuthors) UpdateAuthor(author *Author) (*Author, error) {
+	r.authors[author.Id] = *author
+	return author, nil
+}
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B, truncated to six digits.
+	key := []byte("12345678901234567890")
+	for _, tc := range []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{2000000000, "279037"},
+	} {
+		if got := totpCode(key, tc.unix/totpPeriod); got != tc.want {
+			t.Errorf("code at %d: got %s, want %s", tc.unix, got, tc.want)
+		}
+	}
+}
+
+func TestCheckTOTPRejectsReplay(t *testing.T) {
+	key := []byte("12345678901234567890")
+	author := &Author{TOTPSecret: totpEncoding.EncodeToString(key)}
+	now := time.Unix(1111111109, 0)
+	step := now.Unix() / totpPeriod
+
+	if !checkTOTP(author, totpCode(key, step), now) {
+		t.Fatal("current code rejected")
+	}
+	if author.TOTPLastStep != step {
+		t.Errorf("last step %d, want %d", author.TOTPLastStep, step)
+	}
+	if checkTOTP(author, totpCode(key, step), now) {
+		t.Error("code accepted twice")
+	}
+	if checkTOTP(author, totpCode(key, step-1), now) {
+		t.Error("code from before the last accepted one accepted")
+	}
+	if !checkTOTP(author, totpCode(key, step+1), now) {
+		t.Error("code from the next step rejected")
+	}
+	if checkTOTP(author, totpCode(key, step+3), now) {
+		t.Error("code from outside the allowed skew accepted")
+	}
+}
+
+func TestSecondFactorCodesAreSingleUse(t *testing.T) {
+	author := Author{Id: "ada", Email: "ada@example.com", Role: DefaultRole, EmailVerified: true}
+	repo := &fakeAuthors{authors: map[string]Author{author.Id: author}}
+	s := &accountService{appRepo: repo}
+	ctx := NewContext(context.Background(), &author)
+
+	enrollment, err := s.EnrollTOTP(ctx, author.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := totpEncoding.DecodeString(enrollment.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	step := time.Now().Unix() / totpPeriod
+	recovery, err := s.ConfirmTOTP(ctx, author.Id, totpCode(key, step))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recovery) != recoveryKeys {
+		t.Fatalf("got %d recovery codes, want %d", len(recovery), recoveryKeys)
+	}
+
+	// The code that confirmed enrolment has been spent.
+	if _, err := s.VerifySecondFactor(ctx, author.Id, totpCode(key, step)); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("replayed code: got %v, want ErrInvalidCode", err)
+	}
+	if _, err := s.VerifySecondFactor(ctx, author.Id, totpCode(key, step+1)); err != nil {
+		t.Errorf("next code: %v", err)
+	}
+
+	if _, err := s.VerifySecondFactor(ctx, author.Id, " "+recovery[0]+" "); err != nil {
+		t.Errorf("recovery code: %v", err)
+	}
+	if _, err := s.VerifySecondFactor(ctx, author.Id, recovery[0]); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("reused recovery code: got %v, want ErrInvalidCode", err)
+	}
+	if _, err := s.VerifySecondFactor(ctx, author.Id, recovery[1]); err != nil {
+		t.Errorf("another recovery code: %v", err)
+	}
+	stored, _ := repo.ReadAuthor(author.Id)
+	if err := s.DisableTOTP(NewContext(context.Background(), stored), author.Id, recovery[1]); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("disabling with a used recovery code: got %v, want ErrInvalidCode", err)
+	}
+	if err := s.DisableTOTP(NewContext(context.Background(), stored), author.Id, recovery[2]); err != nil {
+		t.Errorf("disabling with a recovery code: %v", err)
+	}
+	if stored, _ := repo.ReadAuthor(author.Id); stored.TOTPEnabled || stored.TOTPSecret != "" {
+		t.Errorf("two-factor authentication still enabled: %+v", stored)
+	}
+}
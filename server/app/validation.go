@@ -0,0 +1,113 @@
+package app
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"unicode"
+
+	errs "github.com/pkg/errors"
+	"gopkg.in/dealancer/validate.v2"
+)
+
+// Password rules. bcrypt ignores everything past 72 bytes, so longer
+// passwords are refused rather than silently truncated.
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+// validateFields checks each field of the struct v points to against its
+// validate tag and reports every failing field at once.
+func validateFields(v interface{}) error {
+	fields, err := fieldErrors(v)
+	if err != nil {
+		return err
+	}
+	if len(fields) > 0 {
+		return NewValidationError(fields...)
+	}
+	return nil
+}
+
+// validateAuthor is validateFields for an author, also checking that its
+// still plaintext password is strong enough when checkPassword is set.
+func validateAuthor(author *Author, checkPassword bool) error {
+	fields, err := fieldErrors(author)
+	if err != nil {
+		return err
+	}
+	if checkPassword {
+		if weak := passwordProblem(author.Password); weak != nil {
+			fields = append(fields, *weak)
+		}
+	}
+	if len(fields) > 0 {
+		return NewValidationError(fields...)
+	}
+	return nil
+}
+
+// fieldErrors lists the fields of the struct v points to that fail their
+// validate tag, named as in JSON and described by their message tag.
+func fieldErrors(v interface{}) ([]FieldError, error) {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	typ := value.Type()
+	var fields []FieldError
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		rules := field.Tag.Get("validate")
+		if rules == "" {
+			continue
+		}
+		// validate stops at the first failure, so each field is checked on
+		// its own in a single-field struct carrying the same rules.
+		single := reflect.New(reflect.StructOf([]reflect.StructField{{
+			Name: field.Name,
+			Type: field.Type,
+			Tag:  reflect.StructTag(`validate:"` + rules + `"`),
+		}})).Elem()
+		single.Field(0).Set(value.Field(i))
+		err := validate.Validate(single.Interface())
+		if err == nil {
+			continue
+		}
+		var syntax validate.ErrorSyntax
+		if errors.As(err, &syntax) {
+			return nil, errs.Wrap(err, "bad validate tag")
+		}
+		fields = append(fields, FieldError{Field: jsonName(field), Message: field.Tag.Get("message")})
+	}
+	return fields, nil
+}
+
+// passwordProblem describes why a plaintext password is too weak, or returns
+// nil. It must be 8 to 72 bytes and mix upper and lower case with a digit.
+func passwordProblem(password string) *FieldError {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return &FieldError{Field: "password", Message: "must be between 8 and 72 bytes"}
+	}
+	var upper, lower, digit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	if !upper || !lower || !digit {
+		return &FieldError{Field: "password", Message: "must contain an upper case letter, a lower case letter and a digit"}
+	}
+	return nil
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
@@ -0,0 +1,293 @@
+// Package config loads the server's settings. Each setting comes from, in
+// increasing precedence, its default, an optional YAML file, the environment
+// (including a .env file) and command-line flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"example.com/server/app"
+	"example.com/server/repository"
+
+	"github.com/joho/godotenv"
+	errs "github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Storage backends.
+const (
+	BackendDynamoDB = "dynamodb"
+	BackendPostgres = "postgres"
+	BackendSQLite   = "sqlite"
+	BackendMemory   = "memory"
+)
+
+type Config struct {
+	// Addr is where the HTTP server listens.
+	Addr string `yaml:"addr"`
+	// Secret signs access tokens and other tokens the server issues.
+	Secret string `yaml:"secret"`
+	// AppURL is the public address used in links sent by mail.
+	AppURL string `yaml:"app_url"`
+	// PublishInterval is how often scheduled articles are checked.
+	PublishInterval time.Duration `yaml:"publish_interval"`
+	// TrustedProxies are the addresses or CIDR ranges of the reverse proxies
+	// whose X-Forwarded-For header gives the client IP. By default no proxy
+	// is trusted and the client IP is the connection's peer.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	Storage        Storage  `yaml:"storage"`
+	Mail           Mail     `yaml:"mail"`
+	OIDC           OIDC     `yaml:"oidc"`
+}
+
+// Storage selects the repository backend. Only the settings of the selected
+// backend are used.
+type Storage struct {
+	Backend  string                    `yaml:"backend"`
+	Postgres repository.PostgresConfig `yaml:"postgres"`
+	DynamoDB repository.DynamoDBConfig `yaml:"dynamodb"`
+	SQLite   repository.SQLiteConfig   `yaml:"sqlite"`
+}
+
+// Mail sends through SMTP when SMTPHost is set and otherwise writes messages
+// to LogFile, or stdout, for local development.
+type Mail struct {
+	SMTPHost     string `yaml:"smtp_host"`
+	SMTPPort     string `yaml:"smtp_port"`
+	SMTPUsername string `yaml:"smtp_username"`
+	SMTPPassword string `yaml:"smtp_password"`
+	From         string `yaml:"from"`
+	LogFile      string `yaml:"log_file"`
+}
+
+// OIDC enables social login when Issuer is set.
+type OIDC struct {
+	Issuer       string `yaml:"issuer"`
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+	RedirectURL  string `yaml:"redirect_url"`
+}
+
+// Default is the configuration before any file, variable or flag is read.
+func Default() *Config {
+	return &Config{
+		Addr:            ":5000",
+		PublishInterval: app.DefaultPublishInterval,
+		Storage: Storage{
+			Backend:  BackendDynamoDB,
+			Postgres: repository.PostgresConfig{Port: "5432"},
+			SQLite:   repository.SQLiteConfig{Path: "modart.db"},
+		},
+		Mail: Mail{SMTPPort: "587"},
+	}
+}
+
+// Load reads the configuration from args, the command-line flags, and the
+// sources below them. The YAML file is named by the -config flag or
+// CONFIG_FILE. It returns flag.ErrHelp when args ask for usage, which has
+// then been printed. Callers validate the parts they use.
+func Load(args []string) (*Config, error) {
+	if err := godotenv.Load(".env"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, errs.Wrap(err, "loading .env")
+	}
+
+	var file, addr, backend, sqlitePath, interval string
+	flags := flag.NewFlagSet("modart", flag.ContinueOnError)
+	flags.StringVar(&file, "config", os.Getenv("CONFIG_FILE"), "YAML configuration `file`")
+	flags.StringVar(&addr, "addr", "", "`address` to listen on (ADDR)")
+	flags.StringVar(&backend, "storage", "", "storage `backend`: dynamodb, postgres, sqlite or memory (STORAGE)")
+	flags.StringVar(&sqlitePath, "sqlite-path", "", "SQLite database `file` (SQLITE_PATH)")
+	flags.StringVar(&interval, "publish-interval", "", "`duration` between checks for scheduled articles (PUBLISH_INTERVAL)")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	cfg := Default()
+	if file != "" {
+		if err := cfg.readFile(file); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.readEnv(); err != nil {
+		return nil, err
+	}
+	for _, s := range []struct {
+		value string
+		field *string
+	}{
+		{addr, &cfg.Addr},
+		{backend, &cfg.Storage.Backend},
+		{sqlitePath, &cfg.Storage.SQLite.Path},
+	} {
+		if s.value != "" {
+			*s.field = s.value
+		}
+	}
+	if interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, fmt.Errorf("-publish-interval: %v", err)
+		}
+		cfg.PublishInterval = d
+	}
+	return cfg, nil
+}
+
+func (cfg *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errs.Wrap(err, "reading configuration")
+	}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return errs.Wrapf(err, "parsing %s", path)
+	}
+	return nil
+}
+
+// readEnv overrides cfg with the environment variables that are set. Their
+// names predate the YAML file, hence the mix.
+func (cfg *Config) readEnv() error {
+	for name, field := range map[string]*string{
+		"ADDR":                     &cfg.Addr,
+		"SECRET":                   &cfg.Secret,
+		"APP_URL":                  &cfg.AppURL,
+		"STORAGE":                  &cfg.Storage.Backend,
+		"DATABASE_HOST":            &cfg.Storage.Postgres.Host,
+		"DATABASE_PORT":            &cfg.Storage.Postgres.Port,
+		"POSTGRES_USER":            &cfg.Storage.Postgres.User,
+		"POSTGRES_DB":              &cfg.Storage.Postgres.DBName,
+		"POSTGRES_PASSWORD":        &cfg.Storage.Postgres.Password,
+		"DYNAMODB_ENDPOINT":        &cfg.Storage.DynamoDB.Endpoint,
+		"DYNAMODB_USERS_TABLE":     &cfg.Storage.DynamoDB.UsersTable,
+		"DYNAMODB_ARTICLES_TABLE":  &cfg.Storage.DynamoDB.ArticlesTable,
+		"DYNAMODB_SESSIONS_TABLE":  &cfg.Storage.DynamoDB.SessionsTable,
+		"DYNAMODB_API_KEYS_TABLE":  &cfg.Storage.DynamoDB.APIKeysTable,
+		"DYNAMODB_REVISIONS_TABLE": &cfg.Storage.DynamoDB.RevisionsTable,
+		"DYNAMODB_ATTEMPTS_TABLE":  &cfg.Storage.DynamoDB.AttemptsTable,
+		"DYNAMODB_EMAILS_TABLE":    &cfg.Storage.DynamoDB.EmailsTable,
+		"SQLITE_PATH":              &cfg.Storage.SQLite.Path,
+		"SMTP_HOST":                &cfg.Mail.SMTPHost,
+		"SMTP_PORT":                &cfg.Mail.SMTPPort,
+		"SMTP_USERNAME":            &cfg.Mail.SMTPUsername,
+		"SMTP_PASSWORD":            &cfg.Mail.SMTPPassword,
+		"MAIL_FROM":                &cfg.Mail.From,
+		"MAIL_LOG_FILE":            &cfg.Mail.LogFile,
+		"OIDC_ISSUER":              &cfg.OIDC.Issuer,
+		"OIDC_CLIENT_ID":           &cfg.OIDC.ClientID,
+		"OIDC_CLIENT_SECRET":       &cfg.OIDC.ClientSecret,
+		"OIDC_REDIRECT_URL":        &cfg.OIDC.RedirectURL,
+	} {
+		if value := os.Getenv(name); value != "" {
+			*field = value
+		}
+	}
+	if value := os.Getenv("PUBLISH_INTERVAL"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("PUBLISH_INTERVAL: %v", err)
+		}
+		cfg.PublishInterval = d
+	}
+	if value := os.Getenv("TRUSTED_PROXIES"); value != "" {
+		cfg.TrustedProxies = nil
+		for _, proxy := range strings.Split(value, ",") {
+			cfg.TrustedProxies = append(cfg.TrustedProxies, strings.TrimSpace(proxy))
+		}
+	}
+	return nil
+}
+
+// problems collects what is wrong with a configuration.
+type problems []string
+
+func (p *problems) require(value, setting, env string) {
+	if value == "" {
+		*p = append(*p, fmt.Sprintf("%s (%s) is required", setting, env))
+	}
+}
+
+func (p problems) err() error {
+	if len(p) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration: %s", strings.Join(p, "; "))
+}
+
+// Validate reports every missing or invalid setting the server needs at
+// once, each by its YAML path and environment variable.
+func (cfg *Config) Validate() error {
+	p := cfg.Storage.check()
+	p.require(cfg.Addr, "addr", "ADDR")
+	p.require(cfg.Secret, "secret", "SECRET")
+	// Mail is always sent, if only to the log, and its links are built on
+	// AppURL.
+	p.require(cfg.AppURL, "app_url", "APP_URL")
+	if cfg.AppURL != "" && !isWebURL(cfg.AppURL) {
+		p = append(p, fmt.Sprintf("app_url (APP_URL) %q is not an absolute http or https URL", cfg.AppURL))
+	}
+	if cfg.PublishInterval <= 0 {
+		p = append(p, "publish_interval (PUBLISH_INTERVAL) must be positive")
+	}
+	for _, proxy := range cfg.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				p = append(p, fmt.Sprintf("trusted_proxies (TRUSTED_PROXIES) %q is not an IP address or CIDR range", proxy))
+			}
+		}
+	}
+	if cfg.Mail.SMTPHost != "" {
+		p.require(cfg.Mail.SMTPPort, "mail.smtp_port", "SMTP_PORT")
+		p.require(cfg.Mail.From, "mail.from", "MAIL_FROM")
+	}
+	if cfg.OIDC.Issuer != "" {
+		p.require(cfg.OIDC.ClientID, "oidc.client_id", "OIDC_CLIENT_ID")
+		p.require(cfg.OIDC.RedirectURL, "oidc.redirect_url", "OIDC_REDIRECT_URL")
+	}
+	return p.err()
+}
+
+func isWebURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Validate reports what is missing to open the selected backend, for
+// commands that need nothing else.
+func (s Storage) Validate() error {
+	return s.check().err()
+}
+
+func (s Storage) check() problems {
+	var p problems
+	switch s.Backend {
+	case BackendDynamoDB:
+		p.require(s.DynamoDB.UsersTable, "storage.dynamodb.users_table", "DYNAMODB_USERS_TABLE")
+		p.require(s.DynamoDB.ArticlesTable, "storage.dynamodb.articles_table", "DYNAMODB_ARTICLES_TABLE")
+		p.require(s.DynamoDB.SessionsTable, "storage.dynamodb.sessions_table", "DYNAMODB_SESSIONS_TABLE")
+		p.require(s.DynamoDB.APIKeysTable, "storage.dynamodb.api_keys_table", "DYNAMODB_API_KEYS_TABLE")
+		p.require(s.DynamoDB.RevisionsTable, "storage.dynamodb.revisions_table", "DYNAMODB_REVISIONS_TABLE")
+		p.require(s.DynamoDB.AttemptsTable, "storage.dynamodb.attempts_table", "DYNAMODB_ATTEMPTS_TABLE")
+		p.require(s.DynamoDB.EmailsTable, "storage.dynamodb.emails_table", "DYNAMODB_EMAILS_TABLE")
+	case BackendPostgres:
+		p.require(s.Postgres.Host, "storage.postgres.host", "DATABASE_HOST")
+		p.require(s.Postgres.Port, "storage.postgres.port", "DATABASE_PORT")
+		p.require(s.Postgres.User, "storage.postgres.user", "POSTGRES_USER")
+		p.require(s.Postgres.DBName, "storage.postgres.dbname", "POSTGRES_DB")
+	case BackendSQLite:
+		p.require(s.SQLite.Path, "storage.sqlite.path", "SQLITE_PATH")
+	case BackendMemory:
+	default:
+		p = append(p, fmt.Sprintf("storage.backend (STORAGE) %q is not one of dynamodb, postgres, sqlite or memory", s.Backend))
+	}
+	return p
+}
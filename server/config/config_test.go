@@ -0,0 +1,211 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/server/app"
+	"example.com/server/config"
+	"example.com/server/repository"
+)
+
+// clearEnv unsets, for the test, every variable the cases below read, so the
+// environment the tests run in does not leak into them. Load ignores empty
+// variables.
+func clearEnv(t *testing.T) {
+	for _, name := range []string{"CONFIG_FILE", "ADDR", "SECRET", "APP_URL", "STORAGE", "SQLITE_PATH", "PUBLISH_INTERVAL", "DATABASE_PORT", "TRUSTED_PROXIES"} {
+		t.Setenv(name, "")
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "modart.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CONFIG_FILE", writeFile(t, `
+addr: ":6000"
+app_url: https://yaml.example.com
+publish_interval: 2m
+storage:
+  backend: sqlite
+  sqlite:
+    path: yaml.db
+`))
+	t.Setenv("ADDR", ":7000")
+	t.Setenv("SQLITE_PATH", "env.db")
+
+	cfg, err := config.Load([]string{"-addr", ":8000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		setting, got, want string
+	}{
+		{"storage.postgres.port, from its default", cfg.Storage.Postgres.Port, "5432"},
+		{"storage.backend, from YAML", cfg.Storage.Backend, config.BackendSQLite},
+		{"app_url, from YAML", cfg.AppURL, "https://yaml.example.com"},
+		{"storage.sqlite.path, from the environment over YAML", cfg.Storage.SQLite.Path, "env.db"},
+		{"addr, from flags over the environment and YAML", cfg.Addr, ":8000"},
+	} {
+		if c.got != c.want {
+			t.Errorf("%s: got %q, want %q", c.setting, c.got, c.want)
+		}
+	}
+	if cfg.PublishInterval != 2*time.Minute {
+		t.Errorf("publish_interval: got %v, want 2m", cfg.PublishInterval)
+	}
+
+	t.Setenv("PUBLISH_INTERVAL", "3m")
+	if cfg, err = config.Load([]string{"-publish-interval", "4m"}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PublishInterval != 4*time.Minute {
+		t.Errorf("publish_interval: got %v, want the flag's 4m", cfg.PublishInterval)
+	}
+	if cfg, err = config.Load(nil); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PublishInterval != 3*time.Minute {
+		t.Errorf("publish_interval: got %v, want the environment's 3m", cfg.PublishInterval)
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	clearEnv(t)
+	cfg, err := config.Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != ":5000" || cfg.Storage.Backend != config.BackendDynamoDB || cfg.PublishInterval != app.DefaultPublishInterval {
+		t.Errorf("defaults: %+v", cfg)
+	}
+}
+
+func TestLoadConfigFlagOverridesEnvironment(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CONFIG_FILE", writeFile(t, `addr: ":6000"`))
+	cfg, err := config.Load([]string{"-config", writeFile(t, `addr: ":6001"`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != ":6001" {
+		t.Errorf("addr: got %q, want the -config file's", cfg.Addr)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		args []string
+		env  map[string]string
+		want string
+	}{
+		{"unknown YAML setting", []string{"-config", "FILE"}, nil, "field adress not found"},
+		{"missing file", []string{"-config", "/nonexistent/modart.yaml"}, nil, "reading configuration"},
+		{"bad interval variable", nil, map[string]string{"PUBLISH_INTERVAL": "soon"}, "PUBLISH_INTERVAL"},
+		{"bad interval flag", []string{"-publish-interval", "soon"}, nil, "-publish-interval"},
+		{"stray argument", []string{"serve"}, nil, "unexpected arguments: serve"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			clearEnv(t)
+			for name, value := range c.env {
+				t.Setenv(name, value)
+			}
+			args := append([]string(nil), c.args...)
+			for i, arg := range args {
+				if arg == "FILE" {
+					args[i] = writeFile(t, `adress: ":6000"`)
+				}
+			}
+			_, err := config.Load(args)
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Errorf("got %v, want an error mentioning %q", err, c.want)
+			}
+		})
+	}
+}
+
+// valid is a configuration Validate accepts.
+func valid() *config.Config {
+	cfg := config.Default()
+	cfg.Secret = "secret"
+	cfg.AppURL = "https://modart.example.com"
+	cfg.Storage.Backend = config.BackendMemory
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("valid configuration: %v", err)
+	}
+	for _, c := range []struct {
+		name   string
+		change func(*config.Config)
+		want   []string
+	}{
+		{"app_url missing", func(cfg *config.Config) { cfg.AppURL = "" }, []string{"app_url (APP_URL) is required"}},
+		{"app_url relative", func(cfg *config.Config) { cfg.AppURL = "modart.example.com" }, []string{`app_url (APP_URL) "modart.example.com" is not an absolute http or https URL`}},
+		{"sqlite path missing", func(cfg *config.Config) {
+			cfg.Storage.Backend = config.BackendSQLite
+			cfg.Storage.SQLite.Path = ""
+		}, []string{"storage.sqlite.path (SQLITE_PATH) is required"}},
+		{"dynamodb emails table missing", func(cfg *config.Config) {
+			cfg.Storage.Backend = config.BackendDynamoDB
+			cfg.Storage.DynamoDB = repository.DynamoDBConfig{
+				UsersTable:     "users",
+				ArticlesTable:  "articles",
+				SessionsTable:  "sessions",
+				APIKeysTable:   "api_keys",
+				RevisionsTable: "revisions",
+				AttemptsTable:  "attempts",
+			}
+		}, []string{"storage.dynamodb.emails_table (DYNAMODB_EMAILS_TABLE) is required"}},
+		{"everything at once", func(cfg *config.Config) {
+			cfg.Addr = ""
+			cfg.Secret = ""
+			cfg.AppURL = ""
+			cfg.PublishInterval = 0
+			cfg.TrustedProxies = []string{"10.0.0.0/8", "proxy"}
+			cfg.Storage.Backend = "mysql"
+			cfg.Mail.SMTPHost = "smtp.example.com"
+			cfg.OIDC.Issuer = "https://accounts.example.com"
+		}, []string{
+			`storage.backend (STORAGE) "mysql" is not one of`,
+			"addr (ADDR) is required",
+			"secret (SECRET) is required",
+			"app_url (APP_URL) is required",
+			"publish_interval (PUBLISH_INTERVAL) must be positive",
+			`trusted_proxies (TRUSTED_PROXIES) "proxy" is not an IP address or CIDR range`,
+			"mail.from (MAIL_FROM) is required",
+			"oidc.client_id (OIDC_CLIENT_ID) is required",
+			"oidc.redirect_url (OIDC_REDIRECT_URL) is required",
+		}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := valid()
+			c.change(cfg)
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("Validate accepted the configuration")
+			}
+			for _, want := range c.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("%v\ndoes not mention %q", err, want)
+				}
+			}
+			if got := strings.Count(err.Error(), ";") + 1; got != len(c.want) {
+				t.Errorf("%v\nreports %d problems, want %d", err, got, len(c.want))
+			}
+		})
+	}
+}
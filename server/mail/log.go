@@ -0,0 +1,40 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"example.com/server/app"
+)
+
+type logMailer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewLogMailer writes every message to w instead of delivering it, for local
+// development and tests.
+func NewLogMailer(w io.Writer) app.Mailer {
+	return &logMailer{w: w}
+}
+
+// NewFileMailer appends every message to the file at path.
+func NewFileMailer(path string) (app.Mailer, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return NewLogMailer(f), nil
+}
+
+func (m *logMailer) Send(ctx context.Context, msg app.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := fmt.Fprintf(m.w, "--- %s\nTo: %s\nSubject: %s\n\n%s\n",
+		time.Now().UTC().Format(time.RFC3339), msg.To, msg.Subject, msg.Body)
+	return err
+}
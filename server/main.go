@@ -1,10 +1,84 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
 	routes "example.com/server/api"
+	"example.com/server/config"
 )
 
+// shutdownTimeout is how long in-flight requests get to finish on shutdown.
+const shutdownTimeout = 10 * time.Second
+
 func main() {
-	routes.InitGinRoute().Run(":5000")
+	args := os.Args[1:]
+	var err error
+	if len(args) > 0 && args[0] == "migrate" {
+		err = runMigrate(args[1:])
+	} else {
+		err = run(args)
+	}
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "modart: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run serves until interrupted, or returns why it could not.
+func run(args []string) error {
+	cfg, err := config.Load(args)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	router, scheduler, err := routes.InitGinRoute(cfg)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Addr: cfg.Addr, Handler: router}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		scheduler.Run(ctx)
+	}()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			serveErr <- err
+		}
+	}()
 
+	select {
+	case <-ctx.Done():
+	case err = <-serveErr:
+	}
+	stop()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("shutting down: %v", err)
+	}
+	wg.Wait()
+	return err
 }
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"example.com/server/config"
+	"example.com/server/repository"
+)
+
+const migrateUsage = "usage: modart migrate up|down|status [flags]"
+
+// runMigrate runs `modart migrate`: up applies every pending migration, down
+// reverts the latest one and status lists them all. DynamoDB has backfills
+// of items stored before an attribute existed instead, which cannot be
+// reverted.
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	command := args[0]
+	if command != "up" && command != "down" && command != "status" {
+		return fmt.Errorf("unknown migrate command %q; %s", command, migrateUsage)
+	}
+	cfg, err := config.Load(args[1:])
+	if err != nil {
+		return err
+	}
+	if err := cfg.Storage.Validate(); err != nil {
+		return err
+	}
+	if cfg.Storage.Backend == config.BackendDynamoDB {
+		return runBackfills(command, cfg.Storage.DynamoDB)
+	}
+	migrator, err := newMigrator(cfg.Storage)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+	case "down":
+		reverted, err := migrator.Down()
+		if err != nil {
+			return err
+		}
+		if reverted == nil {
+			fmt.Println("no migration to revert")
+			return nil
+		}
+		fmt.Printf("reverted %04d_%s\n", reverted.Version, reverted.Name)
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "MIGRATION\tAPPLIED")
+		for _, status := range statuses {
+			applied := "pending"
+			if status.Applied {
+				applied = time.Unix(status.AppliedAt, 0).UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d_%s\t%s\n", status.Version, status.Name, applied)
+		}
+		return w.Flush()
+	}
+	return nil
+}
+
+// newMigrator opens the SQL backend cfg selects for migrating.
+func newMigrator(cfg config.Storage) (*repository.Migrator, error) {
+	switch cfg.Backend {
+	case config.BackendPostgres:
+		return repository.NewPostgresMigrator(cfg.Postgres)
+	case config.BackendSQLite:
+		return repository.NewSQLiteMigrator(cfg.SQLite)
+	default:
+		return nil, fmt.Errorf("storage backend %s has no schema to migrate", cfg.Backend)
+	}
+}
+
+// runBackfills is runMigrate for DynamoDB.
+func runBackfills(command string, cfg repository.DynamoDBConfig) error {
+	db, err := repository.InitDynamoDB(cfg)
+	if err != nil {
+		return err
+	}
+	switch command {
+	case "up":
+		results, err := db.Backfill()
+		backfilled := 0
+		for _, result := range results {
+			if result.Items > 0 {
+				fmt.Printf("backfilled %s: %d items\n", result.Name, result.Items)
+				backfilled += result.Items
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if backfilled == 0 {
+			fmt.Println("items are up to date")
+		}
+	case "down":
+		return errors.New("DynamoDB backfills cannot be reverted")
+	case "status":
+		results, err := db.PendingBackfills()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "BACKFILL\tPENDING ITEMS")
+		for _, result := range results {
+			fmt.Fprintf(w, "%s\t%d\n", result.Name, result.Items)
+		}
+		return w.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"example.com/server/oidc/oidctest"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const redirectURL = "http://modart.test/users/login/oidc/callback"
+
+// authorize starts a flow against issuer and has the issuer approve it with
+// claims, returning the code and the verifier and nonce the flow used.
+func authorize(t *testing.T, issuer *oidctest.Issuer, p *Provider, claims jwt.MapClaims) (code, verifier, nonce string) {
+	t.Helper()
+	verifier, challenge, err := NewPKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce, err = RandomString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := p.AuthCodeURL(context.Background(), "state", nonce, challenge)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code, _ = issuer.Authorize(t, authURL, claims)
+	return code, verifier, nonce
+}
+
+func TestExchange(t *testing.T) {
+	issuer := oidctest.NewIssuer(t, "modart")
+	p := NewProvider(issuer.URL, "modart", "secret", redirectURL)
+	code, verifier, nonce := authorize(t, issuer, p, jwt.MapClaims{"sub": "42", "email": "grace@example.com"})
+
+	identity, err := p.Exchange(context.Background(), code, verifier, nonce)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	want := Identity{
+		Issuer:        issuer.URL,
+		Subject:       "42",
+		Email:         "grace@example.com",
+		EmailVerified: true,
+		GivenName:     "Ada",
+		FamilyName:    "Lovelace",
+	}
+	if *identity != want {
+		t.Errorf("got %+v, want %+v", *identity, want)
+	}
+	if _, err := p.Exchange(context.Background(), code, verifier, nonce); err == nil {
+		t.Error("a code was redeemed twice")
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	issuer := oidctest.NewIssuer(t, "modart")
+	p := NewProvider(issuer.URL, "modart", "secret", redirectURL)
+	code, _, nonce := authorize(t, issuer, p, nil)
+	other, _, err := NewPKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Exchange(context.Background(), code, other, nonce); err == nil {
+		t.Error("Exchange accepted a code with another flow's PKCE verifier")
+	}
+}
+
+func TestExchangeRejectsNonceMismatch(t *testing.T) {
+	for name, claims := range map[string]jwt.MapClaims{
+		"other nonce": {"nonce": "replayed"},
+		"no nonce":    {"nonce": ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			issuer := oidctest.NewIssuer(t, "modart")
+			p := NewProvider(issuer.URL, "modart", "secret", redirectURL)
+			code, verifier, nonce := authorize(t, issuer, p, claims)
+			if _, err := p.Exchange(context.Background(), code, verifier, nonce); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("got %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestExchangeRejectsBadSignature(t *testing.T) {
+	issuer := oidctest.NewIssuer(t, "modart")
+	forger, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.SigningKey = forger
+	p := NewProvider(issuer.URL, "modart", "secret", redirectURL)
+	code, verifier, nonce := authorize(t, issuer, p, nil)
+	if _, err := p.Exchange(context.Background(), code, verifier, nonce); err == nil {
+		t.Error("Exchange accepted an ID token signed with a key not in the JWKS")
+	}
+}
+
+func TestExchangeRejectsInvalidClaims(t *testing.T) {
+	for name, claims := range map[string]jwt.MapClaims{
+		"other audience": {"aud": "someone-else"},
+		"other issuer":   {"iss": "https://evil.example.com"},
+		"expired":        {"exp": time.Now().Add(-time.Minute).Unix()},
+		"no subject":     {"sub": ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			issuer := oidctest.NewIssuer(t, "modart")
+			p := NewProvider(issuer.URL, "modart", "secret", redirectURL)
+			code, verifier, nonce := authorize(t, issuer, p, claims)
+			if _, err := p.Exchange(context.Background(), code, verifier, nonce); err == nil {
+				t.Error("Exchange accepted the ID token")
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+// Package oidctest is a fake OpenID Connect issuer for testing relying
+// parties. It serves discovery, a JWKS and a token endpoint that enforces
+// PKCE, and signs ID tokens with a key of its own:
+//
+//	issuer := oidctest.NewIssuer(t, "client-id")
+//	provider := oidc.NewProvider(issuer.URL, "client-id", "secret", redirectURL)
+//	// send the user to provider.AuthCodeURL(...), then:
+//	code, state := issuer.Authorize(t, authURL, jwt.MapClaims{"email": "ada@example.com"})
+//
+// There is no login page: Authorize stands in for the user approving the
+// request at the authorization endpoint.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// KeyID names the issuer's signing key in its JWKS and ID tokens.
+const KeyID = "test-key"
+
+// Issuer is a running fake issuer. Its URL is the issuer identifier.
+type Issuer struct {
+	*httptest.Server
+	ClientID string
+	// Key is published in the JWKS. ID tokens are signed with SigningKey,
+	// which is Key unless a test swaps it to forge a signature.
+	Key        *rsa.PrivateKey
+	SigningKey *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]grant
+}
+
+// grant is an authorization code waiting to be redeemed.
+type grant struct {
+	challenge   string
+	redirectURI string
+	claims      jwt.MapClaims
+}
+
+// NewIssuer starts an issuer for clientID, stopped when t ends.
+func NewIssuer(t *testing.T, clientID string) *Issuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating issuer key: %v", err)
+	}
+	issuer := &Issuer{
+		ClientID:   clientID,
+		Key:        key,
+		SigningKey: key,
+		grants:     map[string]grant{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", issuer.serveDiscovery)
+	mux.HandleFunc("/jwks", issuer.serveJWKS)
+	mux.HandleFunc("/token", issuer.serveToken)
+	issuer.Server = httptest.NewServer(mux)
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+// Authorize approves the authorization request at authURL, as the issuer's
+// login page would, and returns the code and state to send to the redirect
+// URI. The ID token for the code carries the request's nonce and claims,
+// which may override any default claim, including the nonce.
+func (i *Issuer) Authorize(t *testing.T, authURL string, claims jwt.MapClaims) (code, state string) {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parsing authorization URL: %v", err)
+	}
+	q := u.Query()
+	if got := u.Scheme + "://" + u.Host + u.Path; got != i.URL+"/authorize" {
+		t.Fatalf("authorization URL %s is not this issuer's", got)
+	}
+	if q.Get("client_id") != i.ClientID || q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("unexpected authorization request %s", u.RawQuery)
+	}
+	merged := jwt.MapClaims{
+		"iss":            i.URL,
+		"aud":            i.ClientID,
+		"sub":            "subject-1",
+		"email":          "ada@example.com",
+		"email_verified": true,
+		"given_name":     "Ada",
+		"family_name":    "Lovelace",
+		"nonce":          q.Get("nonce"),
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Minute).Unix(),
+	}
+	for name, value := range claims {
+		merged[name] = value
+	}
+	code = base64.RawURLEncoding.EncodeToString(randomBytes(t))
+	i.mu.Lock()
+	i.grants[code] = grant{
+		challenge:   q.Get("code_challenge"),
+		redirectURI: q.Get("redirect_uri"),
+		claims:      merged,
+	}
+	i.mu.Unlock()
+	return code, q.Get("state")
+}
+
+func (i *Issuer) serveDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 i.URL,
+		"authorization_endpoint": i.URL + "/authorize",
+		"token_endpoint":         i.URL + "/token",
+		"jwks_uri":               i.URL + "/jwks",
+	})
+}
+
+func (i *Issuer) serveJWKS(w http.ResponseWriter, r *http.Request) {
+	encode := func(n *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(n.Bytes())
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": KeyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   encode(i.Key.N),
+			"e":   encode(big.NewInt(int64(i.Key.E))),
+		}},
+	})
+}
+
+// serveToken redeems a code once, for the client it was issued to and only
+// with the verifier of its PKCE challenge.
+func (i *Issuer) serveToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	if clientID, _, ok := r.BasicAuth(); !ok || clientID != i.ClientID {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	i.mu.Lock()
+	grant, ok := i.grants[r.PostForm.Get("code")]
+	delete(i.grants, r.PostForm.Get("code"))
+	i.mu.Unlock()
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || grant.redirectURI != r.PostForm.Get("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, grant.claims)
+	token.Header["kid"] = KeyID
+	idToken, err := token.SignedString(i.SigningKey)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "opaque",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomBytes(t *testing.T) []byte {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	app "example.com/server/app"
+
+	errs "github.com/pkg/errors"
+)
+
+// idCursor is the position after the item with Id, for listings ordered by,
+// or resumable from, the primary key.
+type idCursor struct {
+	Id string `json:"id"`
+}
+
+// encodeCursor turns a repository's position in a listing into the opaque
+// string handed to clients.
+func encodeCursor(position interface{}) (string, error) {
+	raw, err := json.Marshal(position)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// decodeCursor reads a cursor from encodeCursor into position.
+func decodeCursor(cursor string, position interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return errs.Wrap(app.ErrInvalidCursor, err.Error())
+	}
+	if err := json.Unmarshal(raw, position); err != nil {
+		return errs.Wrap(app.ErrInvalidCursor, err.Error())
+	}
+	return nil
+}
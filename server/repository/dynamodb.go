@@ -3,63 +3,97 @@ package repository
 import (
 	"errors"
 	"fmt"
-	"log"
-	"os"
+	"strings"
 
 	app "example.com/server/app"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
 	"github.com/google/uuid"
-	"github.com/joho/godotenv"
+	errs "github.com/pkg/errors"
 )
 
+// Database is the DynamoDB backend. DynamoDB has no full-text search, so
+// articles are searched in a memory index.
 type Database struct {
+	app.SearchIndex
 	Client                          *dynamodb.DynamoDB
 	UserTablename, ArticleTablename string
+	SessionTablename                string
+	APIKeyTablename                 string
+	RevisionTablename               string
+	AttemptTablename                string
+	EmailTablename                  string
 }
 
-func InitDynamoDB() app.AppService {
-	err := godotenv.Load(".env")
-	if err != nil {
-		log.Fatal("Error loading .env file", err)
+// DynamoDBConfig names the tables of the DynamoDB backend. Endpoint
+// overrides the AWS one, e.g. to use DynamoDB Local.
+type DynamoDBConfig struct {
+	Endpoint       string `yaml:"endpoint"`
+	UsersTable     string `yaml:"users_table"`
+	ArticlesTable  string `yaml:"articles_table"`
+	SessionsTable  string `yaml:"sessions_table"`
+	APIKeysTable   string `yaml:"api_keys_table"`
+	RevisionsTable string `yaml:"revisions_table"`
+	AttemptsTable  string `yaml:"attempts_table"`
+	EmailsTable    string `yaml:"emails_table"`
+}
+
+func InitDynamoDB(cfg DynamoDBConfig) (*Database, error) {
+	config := aws.Config{}
+	if cfg.Endpoint != "" {
+		config.Endpoint = aws.String(cfg.Endpoint)
 	}
-	var (
-		UserTablename    = os.Getenv("DYNAMODB_USERS_TABLE")
-		ArticleTablename = os.Getenv("DYNAMODB_ARTICLES_TABLE")
-	)
-	fmt.Println()
-	sess := session.Must(session.NewSessionWithOptions(session.Options{
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            config,
 		SharedConfigState: session.SharedConfigEnable,
-	}))
+	})
+	if err != nil {
+		return nil, errs.Wrap(err, "creating AWS session")
+	}
 
 	return &Database{
-		Client:           dynamodb.New(sess),
-		UserTablename:    UserTablename,
-		ArticleTablename: ArticleTablename,
-	}
-}
-func (db *Database) LoginAuthor(email string) (*app.Author, error) {
-	return &app.Author{}, nil
+		SearchIndex:       NewMemorySearchIndex(),
+		Client:            dynamodb.New(sess),
+		UserTablename:     cfg.UsersTable,
+		ArticleTablename:  cfg.ArticlesTable,
+		SessionTablename:  cfg.SessionsTable,
+		APIKeyTablename:   cfg.APIKeysTable,
+		RevisionTablename: cfg.RevisionsTable,
+		AttemptTablename:  cfg.AttemptsTable,
+		EmailTablename:    cfg.EmailsTable,
+	}, nil
 }
 func (db *Database) CreateAuthor(author *app.Author) (*app.Author, error) {
 	author.Id = uuid.New().String()
-	entityParsed, err := dynamodbattribute.MarshalMap(author)
+	author.Version = 1
+	entityParsed, err := authorItem(*author)
 	if err != nil {
 		return &app.Author{}, err
 	}
 
-	input := &dynamodb.PutItemInput{
-		Item:      entityParsed,
-		TableName: aws.String(db.UserTablename),
+	_, err = db.Client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{Put: &dynamodb.Put{
+				Item:                entityParsed,
+				TableName:           aws.String(db.UserTablename),
+				ConditionExpression: aws.String("attribute_not_exists(id)"),
+			}},
+			db.claimEmail(author.Email, author.Id),
+		},
+	})
+	switch failedCondition(err) {
+	case 0:
+		return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("Author with id [ %s ] already exists", author.Id))
+	case 1:
+		return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("Author with email [ %s ] already exists", author.Email))
 	}
-
-	_, err = db.Client.PutItem(input)
 	if err != nil {
-		return &app.Author{}, err
+		return nil, err
 	}
 
 	return author, nil
@@ -78,7 +112,7 @@ func (db *Database) ReadAuthor(id string) (*app.Author, error) {
 	}
 	if result.Item == nil {
 		msg := fmt.Sprintf("Author with id [ %s ] not found", id)
-		return &app.Author{}, errors.New(msg)
+		return nil, errs.Wrap(app.ErrNotFound, msg)
 	}
 	var author app.Author
 	err = dynamodbattribute.UnmarshalMap(result.Item, &author)
@@ -88,92 +122,154 @@ func (db *Database) ReadAuthor(id string) (*app.Author, error) {
 
 	return &author, nil
 }
-func (db *Database) ReadAuthors() ([]*app.Author, error) {
-	authors := []*app.Author{}
-	filt := expression.Name("Id").AttributeNotExists()
-	proj := expression.NamesList(
-		expression.Name("id"),
-	)
-	expr, err := expression.NewBuilder().WithFilter(filt).WithProjection(proj).Build()
-
+func (db *Database) ReadAuthorByEmail(email string) (*app.Author, error) {
+	keyCond := expression.Key("email_lower").Equal(expression.Value(strings.ToLower(email)))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCond).Build()
 	if err != nil {
-		return []*app.Author{}, err
+		return &app.Author{}, err
 	}
-	params := &dynamodb.ScanInput{
+	result, err := db.Client.Query(&dynamodb.QueryInput{
+		TableName:                 aws.String(db.UserTablename),
+		IndexName:                 aws.String(authorsByEmailIndex),
+		KeyConditionExpression:    expr.KeyCondition(),
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
-		FilterExpression:          expr.Filter(),
-		ProjectionExpression:      expr.Projection(),
-		TableName:                 aws.String(db.UserTablename),
+		Limit:                     aws.Int64(1),
+	})
+	if err != nil {
+		return &app.Author{}, err
 	}
-	result, err := db.Client.Scan(params)
-
+	if len(result.Items) == 0 {
+		msg := fmt.Sprintf("Author with email [ %s ] not found", email)
+		return &app.Author{}, errs.Wrap(app.ErrNotFound, msg)
+	}
+	var author app.Author
+	err = dynamodbattribute.UnmarshalMap(result.Items[0], &author)
 	if err != nil {
-		fmt.Println("ERROR", err)
-		return []*app.Author{}, err
+		return &app.Author{}, err
 	}
 
-	for _, item := range result.Items {
-		var author app.Author
-		err = dynamodbattribute.UnmarshalMap(item, &author)
-		authors = append(authors, &author)
-
+	return &author, nil
+}
+func (db *Database) ReadAuthors(page app.PageRequest) (*app.AuthorPage, error) {
+	items, cursor, err := db.scanPage(db.UserTablename, page)
+	if err != nil {
+		return nil, err
 	}
-
-	return authors, nil
+	authors := []*app.Author{}
+	if err := dynamodbattribute.UnmarshalListOfMaps(items, &authors); err != nil {
+		return nil, err
+	}
+	return &app.AuthorPage{Authors: authors, NextCursor: cursor}, nil
 }
+
+// UpdateAuthor moves the author's email claim along with its email, in the
+// same transaction as the versioned write.
 func (db *Database) UpdateAuthor(author *app.Author) (*app.Author, error) {
-	entityParsed, err := dynamodbattribute.MarshalMap(author)
+	stored, err := db.ReadAuthor(author.Id)
 	if err != nil {
-		return &app.Author{}, err
+		return nil, err
 	}
-
-	input := &dynamodb.PutItemInput{
-		Item:      entityParsed,
-		TableName: aws.String(db.UserTablename),
+	next := *author
+	next.Version++
+	entityParsed, err := authorItem(next)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(stored.Email, author.Email) {
+		err = db.putVersioned(db.UserTablename, entityParsed, author.Version)
+		if isConditionalCheckFailed(err) {
+			return nil, db.authorVersionMismatch(author)
+		}
+	} else {
+		var expr expression.Expression
+		expr, err = expression.NewBuilder().WithCondition(atVersion(author.Version)).Build()
+		if err != nil {
+			return nil, err
+		}
+		_, err = db.Client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+			TransactItems: []*dynamodb.TransactWriteItem{
+				{Put: &dynamodb.Put{
+					Item:                      entityParsed,
+					TableName:                 aws.String(db.UserTablename),
+					ConditionExpression:       expr.Condition(),
+					ExpressionAttributeNames:  expr.Names(),
+					ExpressionAttributeValues: expr.Values(),
+				}},
+				db.claimEmail(author.Email, author.Id),
+				db.releaseEmail(stored.Email, author.Id),
+			},
+		})
+		switch failedCondition(err) {
+		case 0:
+			return nil, db.authorVersionMismatch(author)
+		case 1:
+			return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("Author with email [ %s ] already exists", author.Email))
+		}
 	}
-
-	_, err = db.Client.PutItem(input)
 	if err != nil {
-		return &app.Author{}, err
+		return nil, err
 	}
+	return &next, nil
+}
 
-	return author, nil
+// authorVersionMismatch explains a failed versioned write of author: it was
+// deleted or changed since it was read.
+func (db *Database) authorVersionMismatch(author *app.Author) error {
+	if _, err := db.ReadAuthor(author.Id); err != nil {
+		return err
+	}
+	return errs.Wrap(app.ErrVersionMismatch, fmt.Sprintf("Author with id [ %s ] is not at version %d", author.Id, author.Version))
 }
+
+// DeleteAuthor releases the author's email claim with the author.
 func (db *Database) DeleteAuthor(id string) error {
-	input := &dynamodb.DeleteItemInput{
-		Key: map[string]*dynamodb.AttributeValue{
-			"id": {
-				S: aws.String(id),
-			},
+	stored, err := db.ReadAuthor(id)
+	if err != nil {
+		return err
+	}
+	_, err = db.Client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{Delete: &dynamodb.Delete{
+				Key: map[string]*dynamodb.AttributeValue{
+					"id": {
+						S: aws.String(id),
+					},
+				},
+				TableName:           aws.String(db.UserTablename),
+				ConditionExpression: aws.String("attribute_exists(id)"),
+			}},
+			db.releaseEmail(stored.Email, id),
 		},
-		TableName: aws.String(db.UserTablename),
-	}
-
-	res, err := db.Client.DeleteItem(input)
-	if res == nil {
-		return errors.New(fmt.Sprintf("No author to delete: %s", err))
-	}
+	})
 	if err != nil {
-		return errors.New(fmt.Sprintf("Got error calling DeleteItem: %s", err))
+		if failedCondition(err) == 0 {
+			return errs.Wrap(app.ErrNotFound, fmt.Sprintf("Author with id [ %s ] not found", id))
+		}
+		return errs.Wrap(err, "calling TransactWriteItems")
 	}
 	return nil
 }
 func (db *Database) CreateArticle(article *app.Article) (*app.Article, error) {
 	article.Id = uuid.New().String()
+	article.Version = 1
 	entityParsed, err := dynamodbattribute.MarshalMap(article)
 	if err != nil {
 		return &app.Article{}, err
 	}
 
 	input := &dynamodb.PutItemInput{
-		Item:      entityParsed,
-		TableName: aws.String(db.ArticleTablename),
+		Item:                entityParsed,
+		TableName:           aws.String(db.ArticleTablename),
+		ConditionExpression: aws.String("attribute_not_exists(id)"),
 	}
 
 	_, err = db.Client.PutItem(input)
 	if err != nil {
-		return &app.Article{}, err
+		if isConditionalCheckFailed(err) {
+			return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("Article with id [ %s ] already exists", article.Id))
+		}
+		return nil, err
 	}
 
 	return article, nil
@@ -192,7 +288,7 @@ func (db *Database) ReadArticle(id string) (*app.Article, error) {
 	}
 	if result.Item == nil {
 		msg := fmt.Sprintf("Article with id [ %s ] not found", id)
-		return &app.Article{}, errors.New(msg)
+		return nil, errs.Wrap(app.ErrNotFound, msg)
 	}
 	var article app.Article
 	err = dynamodbattribute.UnmarshalMap(result.Item, &article)
@@ -202,60 +298,50 @@ func (db *Database) ReadArticle(id string) (*app.Article, error) {
 
 	return &article, nil
 }
-func (db *Database) ReadArticles() ([]*app.Article, error) {
-	articles := []*app.Article{}
-	filt := expression.Name("Id").AttributeNotExists()
-	proj := expression.NamesList(
-		expression.Name("id"),
-		expression.Name("firstName"),
-		expression.Name("lastName"),
-		expression.Name("email"),
-		expression.Name("articles"),
+func (db *Database) ReadArticles(query app.ArticleQuery, page app.PageRequest) (*app.ArticlePage, error) {
+	var (
+		items  []map[string]*dynamodb.AttributeValue
+		cursor string
+		err    error
 	)
-	expr, err := expression.NewBuilder().WithFilter(filt).WithProjection(proj).Build()
-	if err != nil {
-		return []*app.Article{}, err
-	}
-	params := &dynamodb.ScanInput{
-		ExpressionAttributeNames:  expr.Names(),
-		ExpressionAttributeValues: expr.Values(),
-		FilterExpression:          expr.Filter(),
-		ProjectionExpression:      expr.Projection(),
-		TableName:                 aws.String(db.ArticleTablename),
+	switch {
+	case query.AuthorID != "" || len(query.Statuses) == 1:
+		items, cursor, err = db.queryArticles(query, page)
+	case len(query.Statuses) == 0 && query.MinRate == 0 && query.Since == 0 && query.Until == 0 && len(query.Sort) == 0:
+		items, cursor, err = db.scanPage(db.ArticleTablename, page)
+	default:
+		// Without an author or a single status there is no partition to
+		// query, and filtering a scan of the whole table is what this method
+		// refuses to do.
+		return nil, errs.Wrap(app.ErrUnsupportedQuery, "filtering or sorting articles requires author_id")
 	}
-	result, err := db.Client.Scan(params)
-
 	if err != nil {
-
-		return []*app.Article{}, err
+		return nil, err
 	}
-
-	for _, item := range result.Items {
-		var article app.Article
-		err = dynamodbattribute.UnmarshalMap(item, &article)
-		articles = append(articles, &article)
-
+	articles := []*app.Article{}
+	if err := dynamodbattribute.UnmarshalListOfMaps(items, &articles); err != nil {
+		return nil, err
 	}
-
-	return articles, nil
+	return &app.ArticlePage{Articles: articles, NextCursor: cursor}, nil
 }
 func (db *Database) UpdateArticle(article *app.Article) (*app.Article, error) {
-	entityParsed, err := dynamodbattribute.MarshalMap(article)
+	next := *article
+	next.Version++
+	entityParsed, err := dynamodbattribute.MarshalMap(next)
 	if err != nil {
-		return &app.Article{}, err
+		return nil, err
 	}
-
-	input := &dynamodb.PutItemInput{
-		Item:      entityParsed,
-		TableName: aws.String(db.ArticleTablename),
+	err = db.putVersioned(db.ArticleTablename, entityParsed, article.Version)
+	if isConditionalCheckFailed(err) {
+		if _, readErr := db.ReadArticle(article.Id); readErr != nil {
+			return nil, readErr
+		}
+		return nil, errs.Wrap(app.ErrVersionMismatch, fmt.Sprintf("Article with id [ %s ] is not at version %d", article.Id, article.Version))
 	}
-
-	_, err = db.Client.PutItem(input)
 	if err != nil {
-		return &app.Article{}, err
+		return nil, err
 	}
-
-	return article, nil
+	return &next, nil
 }
 func (db *Database) DeleteArticle(id string) error {
 	input := &dynamodb.DeleteItemInput{
@@ -264,15 +350,91 @@ func (db *Database) DeleteArticle(id string) error {
 				S: aws.String(id),
 			},
 		},
-		TableName: aws.String(db.ArticleTablename),
+		TableName:           aws.String(db.ArticleTablename),
+		ConditionExpression: aws.String("attribute_exists(id)"),
 	}
 
-	res, err := db.Client.DeleteItem(input)
-	if res == nil {
-		return errors.New(fmt.Sprintf("No author to delete: %s", err))
-	}
+	_, err := db.Client.DeleteItem(input)
 	if err != nil {
-		return errors.New(fmt.Sprintf("Got error calling DeleteItem: %s", err))
+		if isConditionalCheckFailed(err) {
+			return errs.Wrap(app.ErrNotFound, fmt.Sprintf("Article with id [ %s ] not found", id))
+		}
+		return errs.Wrap(err, "calling DeleteItem")
 	}
 	return nil
 }
+
+// putVersioned replaces an existing item only while it is still at version.
+// Items written before versions existed have none and count as version 0.
+func (db *Database) putVersioned(table string, item map[string]*dynamodb.AttributeValue, version int64) error {
+	expr, err := expression.NewBuilder().WithCondition(atVersion(version)).Build()
+	if err != nil {
+		return err
+	}
+	_, err = db.Client.PutItem(&dynamodb.PutItemInput{
+		Item:                      item,
+		TableName:                 aws.String(table),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	return err
+}
+
+// atVersion is the condition that an item exists and is at version; items
+// stored before versioning count as version 0.
+func atVersion(version int64) expression.ConditionBuilder {
+	condition := expression.Name("version").Equal(expression.Value(version))
+	if version == 0 {
+		condition = expression.Or(expression.Name("version").AttributeNotExists(), condition)
+	}
+	return expression.Name("id").AttributeExists().And(condition)
+}
+
+// isConditionalCheckFailed reports whether a write was rejected by its
+// ConditionExpression, which is how the existence checks above surface.
+func isConditionalCheckFailed(err error) bool {
+	var aerr awserr.Error
+	return errors.As(err, &aerr) && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
+
+// scanPage reads up to page.Limit items of table in scan order. A single Scan
+// stops at 1MB, so it keeps following LastEvaluatedKey until the page is full
+// or the table is exhausted, and returns the key to resume from as a cursor.
+func (db *Database) scanPage(table string, page app.PageRequest) ([]map[string]*dynamodb.AttributeValue, string, error) {
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(table),
+	}
+	if page.Cursor != "" {
+		var after idCursor
+		if err := decodeCursor(page.Cursor, &after); err != nil {
+			return nil, "", err
+		}
+		input.ExclusiveStartKey = map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(after.Id),
+			},
+		}
+	}
+	var items []map[string]*dynamodb.AttributeValue
+	for {
+		input.Limit = aws.Int64(int64(page.Limit - len(items)))
+		result, err := db.Client.Scan(input)
+		if err != nil {
+			return nil, "", err
+		}
+		items = append(items, result.Items...)
+		if len(result.LastEvaluatedKey) == 0 {
+			return items, "", nil
+		}
+		if len(items) >= page.Limit {
+			var last idCursor
+			if err := dynamodbattribute.UnmarshalMap(result.LastEvaluatedKey, &last); err != nil {
+				return nil, "", err
+			}
+			cursor, err := encodeCursor(last)
+			return items, cursor, err
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"fmt"
+
+	app "example.com/server/app"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	errs "github.com/pkg/errors"
+)
+
+func (db *Database) CreateAPIKey(key *app.APIKey) (*app.APIKey, error) {
+	return db.putAPIKey(key)
+}
+
+func (db *Database) ReadAPIKey(id string) (*app.APIKey, error) {
+	result, err := db.Client.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(db.APIKeyTablename),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	})
+	if err != nil {
+		return &app.APIKey{}, err
+	}
+	if result.Item == nil {
+		msg := fmt.Sprintf("API key with id [ %s ] not found", id)
+		return &app.APIKey{}, errs.Wrap(app.ErrNotFound, msg)
+	}
+	var key app.APIKey
+	err = dynamodbattribute.UnmarshalMap(result.Item, &key)
+	if err != nil {
+		return &app.APIKey{}, err
+	}
+
+	return &key, nil
+}
+
+func (db *Database) ReadAPIKeys(authorID string) ([]*app.APIKey, error) {
+	keys := []*app.APIKey{}
+	filt := expression.Name("author_id").Equal(expression.Value(authorID))
+	expr, err := expression.NewBuilder().WithFilter(filt).Build()
+	if err != nil {
+		return keys, err
+	}
+	params := &dynamodb.ScanInput{
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		FilterExpression:          expr.Filter(),
+		TableName:                 aws.String(db.APIKeyTablename),
+	}
+	var unmarshalErr error
+	err = db.Client.ScanPages(params, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			var key app.APIKey
+			if unmarshalErr = dynamodbattribute.UnmarshalMap(item, &key); unmarshalErr != nil {
+				return false
+			}
+			keys = append(keys, &key)
+		}
+		return true
+	})
+	if err != nil {
+		return []*app.APIKey{}, err
+	}
+	if unmarshalErr != nil {
+		return []*app.APIKey{}, unmarshalErr
+	}
+
+	return keys, nil
+}
+
+func (db *Database) UpdateAPIKey(key *app.APIKey) (*app.APIKey, error) {
+	return db.putAPIKey(key)
+}
+
+func (db *Database) TouchAPIKey(id string, usedAt int64) error {
+	expr, err := expression.NewBuilder().
+		WithCondition(expression.Name("id").AttributeExists().And(expression.Name("revoked_at").Equal(expression.Value(0)))).
+		WithUpdate(expression.Set(expression.Name("last_used_at"), expression.Value(usedAt))).
+		Build()
+	if err != nil {
+		return err
+	}
+	_, err = db.Client.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(db.APIKeyTablename),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+		ConditionExpression:       expr.Condition(),
+		UpdateExpression:          expr.Update(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	if isConditionalCheckFailed(err) {
+		return errs.Wrap(app.ErrNotFound, fmt.Sprintf("unrevoked API key with id [ %s ] not found", id))
+	}
+	return err
+}
+
+func (db *Database) putAPIKey(key *app.APIKey) (*app.APIKey, error) {
+	entityParsed, err := dynamodbattribute.MarshalMap(key)
+	if err != nil {
+		return &app.APIKey{}, err
+	}
+
+	input := &dynamodb.PutItemInput{
+		Item:      entityParsed,
+		TableName: aws.String(db.APIKeyTablename),
+	}
+
+	_, err = db.Client.PutItem(input)
+	if err != nil {
+		return &app.APIKey{}, err
+	}
+
+	return key, nil
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+
+	app "example.com/server/app"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	errs "github.com/pkg/errors"
+)
+
+func (db *Database) ReadAttempts(key string) (*app.LoginAttempts, error) {
+	result, err := db.Client.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(db.AttemptTablename),
+		Key: map[string]*dynamodb.AttributeValue{
+			"key": {
+				S: aws.String(key),
+			},
+		},
+	})
+	if err != nil {
+		return &app.LoginAttempts{}, err
+	}
+	if result.Item == nil {
+		return &app.LoginAttempts{Key: key}, nil
+	}
+	var attempts app.LoginAttempts
+	err = dynamodbattribute.UnmarshalMap(result.Item, &attempts)
+	if err != nil {
+		return &app.LoginAttempts{}, err
+	}
+
+	return &attempts, nil
+}
+
+// RecordFailure counts the failure with a conditional write: an ADD to a
+// key failing within the window, or a fresh item in place of a missing or
+// stale one. Whichever condition a concurrent failure invalidates is retried.
+// The lock is a second write, conditioned on the key being over the
+// threshold and unlocked, so only one of several concurrent failures locks
+// it.
+func (db *Database) RecordFailure(key string, now, since int64, threshold int, lockUntil int64) (*app.LoginAttempts, error) {
+	for try := 0; try < 3; try++ {
+		attempts, err := db.countFailure(key, now, since)
+		if isConditionalCheckFailed(err) {
+			attempts, err = db.resetFailures(key, now, since, threshold, lockUntil)
+		}
+		if isConditionalCheckFailed(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if attempts.Failures < threshold || attempts.LockedUntil > now {
+			return attempts, nil
+		}
+		return db.lockAttempts(attempts, now, threshold, lockUntil)
+	}
+	return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("login attempts for [ %s ] kept changing", key))
+}
+
+// countFailure adds a failure to a key that last failed at or after since.
+func (db *Database) countFailure(key string, now, since int64) (*app.LoginAttempts, error) {
+	cond := expression.Name("last_failure").GreaterThanEqual(expression.Value(since))
+	update := expression.Add(expression.Name("failures"), expression.Value(1)).
+		Set(expression.Name("last_failure"), expression.Value(now))
+	return db.updateAttempts(key, cond, update)
+}
+
+// resetFailures starts the count of a key over, unless another failure has
+// already counted within the window.
+func (db *Database) resetFailures(key string, now, since int64, threshold int, lockUntil int64) (*app.LoginAttempts, error) {
+	attempts := &app.LoginAttempts{Key: key, Failures: 1, LastFailure: now}
+	if threshold <= 1 {
+		attempts.LockedUntil = lockUntil
+	}
+	item, err := dynamodbattribute.MarshalMap(attempts)
+	if err != nil {
+		return nil, err
+	}
+	cond := expression.Name("key").AttributeNotExists().
+		Or(expression.Name("last_failure").LessThan(expression.Value(since)))
+	expr, err := expression.NewBuilder().WithCondition(cond).Build()
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Client.PutItem(&dynamodb.PutItemInput{
+		Item:                      item,
+		TableName:                 aws.String(db.AttemptTablename),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
+
+// lockAttempts locks a key that reached threshold. If a concurrent failure
+// locked it first, the key is returned as that failure left it.
+func (db *Database) lockAttempts(attempts *app.LoginAttempts, now int64, threshold int, lockUntil int64) (*app.LoginAttempts, error) {
+	cond := expression.Name("failures").GreaterThanEqual(expression.Value(threshold)).
+		And(expression.Name("locked_until").LessThanEqual(expression.Value(now)))
+	update := expression.Set(expression.Name("locked_until"), expression.Value(lockUntil))
+	locked, err := db.updateAttempts(attempts.Key, cond, update)
+	if isConditionalCheckFailed(err) {
+		return db.ReadAttempts(attempts.Key)
+	}
+	return locked, err
+}
+
+func (db *Database) updateAttempts(key string, cond expression.ConditionBuilder, update expression.UpdateBuilder) (*app.LoginAttempts, error) {
+	expr, err := expression.NewBuilder().WithCondition(cond).WithUpdate(update).Build()
+	if err != nil {
+		return nil, err
+	}
+	result, err := db.Client.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(db.AttemptTablename),
+		Key: map[string]*dynamodb.AttributeValue{
+			"key": {S: aws.String(key)},
+		},
+		ConditionExpression:       expr.Condition(),
+		UpdateExpression:          expr.Update(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var attempts app.LoginAttempts
+	if err := dynamodbattribute.UnmarshalMap(result.Attributes, &attempts); err != nil {
+		return nil, err
+	}
+	return &attempts, nil
+}
+
+func (db *Database) DeleteAttempts(key string) error {
+	_, err := db.Client.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(db.AttemptTablename),
+		Key: map[string]*dynamodb.AttributeValue{
+			"key": {
+				S: aws.String(key),
+			},
+		},
+	})
+	return err
+}
+
+// CreateAuditEntry only logs the entry; the DynamoDB backend has no audit
+// table.
+func (db *Database) CreateAuditEntry(entry *app.AuditEntry) error {
+	log.Printf("audit: %s subject=%q ip=%s reason=%s", entry.Event, entry.Subject, entry.IP, entry.Reason)
+	return nil
+}
@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"strings"
+
+	app "example.com/server/app"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	errs "github.com/pkg/errors"
+)
+
+// dynamoBackfill rewrites the items of one table that were stored before an
+// attribute existed. DynamoDB has no schema to migrate, so these are what
+// `modart migrate up` runs for it.
+type dynamoBackfill struct {
+	name  string
+	table func(db *Database) string
+	// pending matches the items that still need the backfill; it is also the
+	// condition each rewrite is made under, so backfills can be rerun, or run
+	// alongside the server, without rewriting an item twice.
+	pending expression.ConditionBuilder
+	update  func(item map[string]*dynamodb.AttributeValue) expression.UpdateBuilder
+	// also, when set, lists writes to other tables made in one transaction
+	// with each rewrite; if any of their conditions fails, the backfill
+	// stops with an error.
+	also func(db *Database, item map[string]*dynamodb.AttributeValue) []*dynamodb.TransactWriteItem
+}
+
+var dynamoBackfills = []dynamoBackfill{
+	{
+		// Authors from before email verification existed signed up without
+		// it, so they count as verified rather than losing the right to
+		// publish.
+		name:    "verify_legacy_authors",
+		table:   func(db *Database) string { return db.UserTablename },
+		pending: expression.Name("email_verified").AttributeNotExists(),
+		update: func(map[string]*dynamodb.AttributeValue) expression.UpdateBuilder {
+			return expression.Set(expression.Name("email_verified"), expression.Value(true))
+		},
+	},
+	{
+		// Articles from before the lifecycle existed were all public, but
+		// without a status they are missing from the sparse status index
+		// that lists published articles. They count as published when they
+		// were created.
+		name:    "publish_legacy_articles",
+		table:   func(db *Database) string { return db.ArticleTablename },
+		pending: expression.Name("status").AttributeNotExists(),
+		update: func(item map[string]*dynamodb.AttributeValue) expression.UpdateBuilder {
+			update := expression.Set(expression.Name("status"), expression.Value(app.StatusPublished))
+			var createdAt int64
+			if av, ok := item["created_at"]; ok && dynamodbattribute.Unmarshal(av, &createdAt) == nil {
+				update = update.Set(expression.Name("published_at"), expression.Value(createdAt))
+			}
+			return update
+		},
+	},
+	{
+		// Authors from before emails were unique in any case have neither
+		// the lower-cased email the email index is keyed on nor a claim on
+		// their address. Two authors sharing one must be merged by hand.
+		name:    "claim_author_emails",
+		table:   func(db *Database) string { return db.UserTablename },
+		pending: expression.Name("email_lower").AttributeNotExists(),
+		update: func(item map[string]*dynamodb.AttributeValue) expression.UpdateBuilder {
+			return expression.Set(expression.Name("email_lower"), expression.Value(strings.ToLower(stringAttribute(item, "email"))))
+		},
+		also: func(db *Database, item map[string]*dynamodb.AttributeValue) []*dynamodb.TransactWriteItem {
+			return []*dynamodb.TransactWriteItem{db.claimEmail(stringAttribute(item, "email"), stringAttribute(item, "id"))}
+		},
+	},
+}
+
+// stringAttribute returns the string attribute name of item, or "".
+func stringAttribute(item map[string]*dynamodb.AttributeValue, name string) string {
+	if av, ok := item[name]; ok && av.S != nil {
+		return *av.S
+	}
+	return ""
+}
+
+// BackfillResult is how many items a backfill rewrote, or has left to.
+type BackfillResult struct {
+	Name  string
+	Items int
+}
+
+// Backfill runs every backfill over the items that still need it and returns
+// how many items each rewrote.
+func (db *Database) Backfill() ([]BackfillResult, error) {
+	results := make([]BackfillResult, 0, len(dynamoBackfills))
+	for _, backfill := range dynamoBackfills {
+		result := BackfillResult{Name: backfill.name}
+		err := db.scanPending(backfill, func(item map[string]*dynamodb.AttributeValue) error {
+			updated, err := db.backfillItem(backfill, item)
+			if updated {
+				result.Items++
+			}
+			return err
+		})
+		results = append(results, result)
+		if err != nil {
+			return results, errs.Wrapf(err, "backfilling %s", backfill.name)
+		}
+	}
+	return results, nil
+}
+
+// PendingBackfills counts the items each backfill has yet to rewrite.
+func (db *Database) PendingBackfills() ([]BackfillResult, error) {
+	results := make([]BackfillResult, 0, len(dynamoBackfills))
+	for _, backfill := range dynamoBackfills {
+		result := BackfillResult{Name: backfill.name}
+		err := db.scanPending(backfill, func(map[string]*dynamodb.AttributeValue) error {
+			result.Items++
+			return nil
+		})
+		if err != nil {
+			return nil, errs.Wrapf(err, "scanning for %s", backfill.name)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// scanPending calls fn with every item backfill still needs to rewrite.
+func (db *Database) scanPending(backfill dynamoBackfill, fn func(map[string]*dynamodb.AttributeValue) error) error {
+	expr, err := expression.NewBuilder().WithFilter(backfill.pending).Build()
+	if err != nil {
+		return err
+	}
+	var fnErr error
+	err = db.Client.ScanPages(&dynamodb.ScanInput{
+		TableName:                 aws.String(backfill.table(db)),
+		FilterExpression:          expr.Filter(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			if fnErr = fn(item); fnErr != nil {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return fnErr
+}
+
+// backfillItem rewrites item if it still exists and needs it, and reports
+// whether it did.
+func (db *Database) backfillItem(backfill dynamoBackfill, item map[string]*dynamodb.AttributeValue) (bool, error) {
+	expr, err := expression.NewBuilder().
+		WithCondition(expression.Name("id").AttributeExists().And(backfill.pending)).
+		WithUpdate(backfill.update(item)).
+		Build()
+	if err != nil {
+		return false, err
+	}
+	key := map[string]*dynamodb.AttributeValue{"id": item["id"]}
+	if backfill.also != nil {
+		_, err = db.Client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+			TransactItems: append([]*dynamodb.TransactWriteItem{{Update: &dynamodb.Update{
+				TableName:                 aws.String(backfill.table(db)),
+				Key:                       key,
+				ConditionExpression:       expr.Condition(),
+				UpdateExpression:          expr.Update(),
+				ExpressionAttributeNames:  expr.Names(),
+				ExpressionAttributeValues: expr.Values(),
+			}}}, backfill.also(db, item)...),
+		})
+		switch failed := failedCondition(err); {
+		case failed == 0:
+			return false, nil
+		case failed > 0:
+			return false, errs.Errorf("item [ %s ] conflicts with another item", stringAttribute(item, "id"))
+		}
+		return err == nil, err
+	}
+	_, err = db.Client.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                 aws.String(backfill.table(db)),
+		Key:                       key,
+		ConditionExpression:       expr.Condition(),
+		UpdateExpression:          expr.Update(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	if isConditionalCheckFailed(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
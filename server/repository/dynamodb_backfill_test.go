@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"testing"
+
+	app "example.com/server/app"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// backfillSets returns the attributes the named backfill sets on item, with
+// their values; every backfill update is a list of SET actions.
+func backfillSets(t *testing.T, name string, item map[string]*dynamodb.AttributeValue) map[string]interface{} {
+	t.Helper()
+	for _, backfill := range dynamoBackfills {
+		if backfill.name != name {
+			continue
+		}
+		expr, err := expression.NewBuilder().WithUpdate(backfill.update(item)).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sets := map[string]interface{}{}
+		for placeholder, attribute := range expr.Names() {
+			// The builder numbers names and values together, one pair per
+			// SET action.
+			var value interface{}
+			if err := dynamodbattribute.Unmarshal(expr.Values()[":"+placeholder[1:]], &value); err != nil {
+				t.Fatal(err)
+			}
+			sets[*attribute] = value
+		}
+		return sets
+	}
+	t.Fatalf("no backfill %s", name)
+	return nil
+}
+
+func TestPublishLegacyArticles(t *testing.T) {
+	legacy, err := dynamodbattribute.MarshalMap(map[string]interface{}{
+		"id":         "a1",
+		"title":      "Notes",
+		"created_at": int64(1500000000),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sets := backfillSets(t, "publish_legacy_articles", legacy)
+	if sets["status"] != string(app.StatusPublished) || sets["published_at"] != float64(1500000000) {
+		t.Errorf("backfill sets %v", sets)
+	}
+}
+
+func TestClaimAuthorEmails(t *testing.T) {
+	legacy, err := dynamodbattribute.MarshalMap(map[string]interface{}{
+		"id":    "au1",
+		"email": "Ada@Example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sets := backfillSets(t, "claim_author_emails", legacy); sets["email_lower"] != "ada@example.com" {
+		t.Errorf("backfill sets %v", sets)
+	}
+	db := &Database{EmailTablename: "emails"}
+	for _, backfill := range dynamoBackfills {
+		if backfill.name != "claim_author_emails" {
+			continue
+		}
+		also := backfill.also(db, legacy)
+		if len(also) != 1 || *also[0].Put.Item["email"].S != "ada@example.com" || *also[0].Put.Item["author_id"].S != "au1" {
+			t.Errorf("backfill also writes %v", also)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+
+	app "example.com/server/app"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// Author emails are unique in any case. Each author item carries its email
+// lower-cased as email_lower, which the users table's email index is keyed
+// on, and each address is claimed by an item of the emails table, keyed by
+// the lower-cased address, written in the same transaction as the author.
+// Authors from before the claims existed get them from `modart migrate up`.
+const authorsByEmailIndex = "email_lower-index"
+
+// authorItem marshals author with its email_lower attribute.
+func authorItem(author app.Author) (map[string]*dynamodb.AttributeValue, error) {
+	item, err := dynamodbattribute.MarshalMap(author)
+	if err != nil {
+		return nil, err
+	}
+	if author.Email != "" {
+		item["email_lower"] = &dynamodb.AttributeValue{S: aws.String(strings.ToLower(author.Email))}
+	}
+	return item, nil
+}
+
+// claimEmail is the transaction item claiming email for authorID; it fails
+// its condition when another author has the address.
+func (db *Database) claimEmail(email, authorID string) *dynamodb.TransactWriteItem {
+	return &dynamodb.TransactWriteItem{Put: &dynamodb.Put{
+		TableName: aws.String(db.EmailTablename),
+		Item: map[string]*dynamodb.AttributeValue{
+			"email":     {S: aws.String(strings.ToLower(email))},
+			"author_id": {S: aws.String(authorID)},
+		},
+		ConditionExpression:       aws.String("attribute_not_exists(email) OR author_id = :author_id"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":author_id": {S: aws.String(authorID)}},
+	}}
+}
+
+// releaseEmail is the transaction item giving up authorID's claim on email,
+// if it has one.
+func (db *Database) releaseEmail(email, authorID string) *dynamodb.TransactWriteItem {
+	return &dynamodb.TransactWriteItem{Delete: &dynamodb.Delete{
+		TableName:                 aws.String(db.EmailTablename),
+		Key:                       map[string]*dynamodb.AttributeValue{"email": {S: aws.String(strings.ToLower(email))}},
+		ConditionExpression:       aws.String("attribute_not_exists(email) OR author_id = :author_id"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":author_id": {S: aws.String(authorID)}},
+	}}
+}
+
+// failedCondition returns the index of the first item of a cancelled
+// transaction whose condition failed, or -1 when err is not that.
+func failedCondition(err error) int {
+	var cancelled *dynamodb.TransactionCanceledException
+	if !errors.As(err, &cancelled) {
+		return -1
+	}
+	for i, reason := range cancelled.CancellationReasons {
+		if reason.Code != nil && *reason.Code == "ConditionalCheckFailed" {
+			return i
+		}
+	}
+	return -1
+}
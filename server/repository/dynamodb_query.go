@@ -0,0 +1,174 @@
+package repository
+
+import (
+	app "example.com/server/app"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	errs "github.com/pkg/errors"
+)
+
+// Global secondary indexes on the articles table. The author indexes let one
+// author's articles be read in created_at or rate order, and the status index
+// serves the public listing, without a scan. Items written before statuses
+// existed are absent from the sparse status index until `modart migrate up`
+// backfills them.
+const (
+	articlesByCreatedIndex = "author_id-created_at-index"
+	articlesByRateIndex    = "author_id-rate-index"
+	articlesByStatusIndex  = "status-created_at-index"
+)
+
+// articleKey is the LastEvaluatedKey of a query on one of the article
+// indexes: the table key plus the index keys.
+type articleKey struct {
+	Id       string            `json:"id"`
+	AuthorID string            `json:"author_id,omitempty"`
+	Status   app.ArticleStatus `json:"status,omitempty"`
+	CreateAt *int64            `json:"created_at,omitempty"`
+	Rate     *int              `json:"rate,omitempty"`
+}
+
+// queryArticles answers an article query from the index partitioned by its
+// author, or failing that by its only status, and sorted on the requested
+// field. The range of that index becomes part of the key condition; the
+// other predicates filter within the partition.
+func (db *Database) queryArticles(query app.ArticleQuery, page app.PageRequest) ([]map[string]*dynamodb.AttributeValue, string, error) {
+	if len(query.Sort) > 1 {
+		return nil, "", errs.Wrap(app.ErrUnsupportedQuery, "DynamoDB can only sort articles on one field")
+	}
+	sort := app.SortKey{Field: app.SortCreatedAt, Desc: true}
+	if len(query.Sort) == 1 {
+		sort = query.Sort[0]
+	}
+
+	var (
+		index   string
+		status  app.ArticleStatus
+		keyCond expression.KeyConditionBuilder
+		filters []expression.ConditionBuilder
+	)
+	if query.AuthorID != "" {
+		index = articlesByCreatedIndex
+		if sort.Field == app.SortRate {
+			index = articlesByRateIndex
+		}
+		keyCond = expression.Key("author_id").Equal(expression.Value(query.AuthorID))
+		if len(query.Statuses) > 0 {
+			filters = append(filters, statusFilter(query.Statuses))
+		}
+	} else {
+		if sort.Field == app.SortRate {
+			return nil, "", errs.Wrap(app.ErrUnsupportedQuery, "sorting by rate requires author_id")
+		}
+		if len(query.Statuses) != 1 {
+			return nil, "", errs.Wrap(app.ErrUnsupportedQuery, "listing articles without author_id requires exactly one status")
+		}
+		index = articlesByStatusIndex
+		status = query.Statuses[0]
+		keyCond = expression.Key("status").Equal(expression.Value(status))
+	}
+
+	if sort.Field == app.SortRate {
+		if query.MinRate != 0 {
+			keyCond = keyCond.And(expression.Key("rate").GreaterThanEqual(expression.Value(query.MinRate)))
+		}
+		if query.Since != 0 {
+			filters = append(filters, expression.Name("created_at").GreaterThanEqual(expression.Value(query.Since)))
+		}
+		if query.Until != 0 {
+			filters = append(filters, expression.Name("created_at").LessThan(expression.Value(query.Until)))
+		}
+	} else {
+		switch {
+		case query.Since != 0 && query.Until != 0:
+			keyCond = keyCond.And(expression.Key("created_at").Between(expression.Value(query.Since), expression.Value(query.Until-1)))
+		case query.Since != 0:
+			keyCond = keyCond.And(expression.Key("created_at").GreaterThanEqual(expression.Value(query.Since)))
+		case query.Until != 0:
+			keyCond = keyCond.And(expression.Key("created_at").LessThan(expression.Value(query.Until)))
+		}
+		if query.MinRate != 0 {
+			filters = append(filters, expression.Name("rate").GreaterThanEqual(expression.Value(query.MinRate)))
+		}
+	}
+
+	builder := expression.NewBuilder().WithKeyCondition(keyCond)
+	switch len(filters) {
+	case 0:
+	case 1:
+		builder = builder.WithFilter(filters[0])
+	default:
+		builder = builder.WithFilter(expression.And(filters[0], filters[1], filters[2:]...))
+	}
+	expr, err := builder.Build()
+	if err != nil {
+		return nil, "", err
+	}
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(db.ArticleTablename),
+		IndexName:                 aws.String(index),
+		KeyConditionExpression:    expr.KeyCondition(),
+		FilterExpression:          expr.Filter(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ScanIndexForward:          aws.Bool(!sort.Desc),
+	}
+	if page.Cursor != "" {
+		var after articleKey
+		if err := decodeCursor(page.Cursor, &after); err != nil {
+			return nil, "", err
+		}
+		if after.AuthorID != query.AuthorID || (index == articlesByRateIndex) != (after.Rate != nil) ||
+			(index == articlesByStatusIndex && after.Status != status) {
+			return nil, "", errs.Wrap(app.ErrInvalidCursor, "cursor is for another query")
+		}
+		start, err := dynamodbattribute.MarshalMap(after)
+		if err != nil {
+			return nil, "", err
+		}
+		input.ExclusiveStartKey = start
+	}
+
+	// Limit caps the items evaluated before filtering, so keep querying
+	// until the page is full or the partition is exhausted.
+	var items []map[string]*dynamodb.AttributeValue
+	for {
+		input.Limit = aws.Int64(int64(page.Limit - len(items)))
+		result, err := db.Client.Query(input)
+		if err != nil {
+			return nil, "", err
+		}
+		items = append(items, result.Items...)
+		if len(result.LastEvaluatedKey) == 0 {
+			return items, "", nil
+		}
+		if len(items) >= page.Limit {
+			var last articleKey
+			if err := dynamodbattribute.UnmarshalMap(result.LastEvaluatedKey, &last); err != nil {
+				return nil, "", err
+			}
+			cursor, err := encodeCursor(last)
+			return items, cursor, err
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// statusFilter matches any of statuses. Items stored before statuses existed
+// have none and count as published.
+func statusFilter(statuses []app.ArticleStatus) expression.ConditionBuilder {
+	operands := make([]expression.OperandBuilder, len(statuses))
+	for i, status := range statuses {
+		operands[i] = expression.Value(status)
+	}
+	cond := expression.Name("status").In(operands[0], operands[1:]...)
+	for _, status := range statuses {
+		if status == app.StatusPublished {
+			return expression.Or(expression.Name("status").AttributeNotExists(), cond)
+		}
+	}
+	return cond
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"fmt"
+	"strconv"
+
+	app "example.com/server/app"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	errs "github.com/pkg/errors"
+)
+
+// Revisions live in their own table keyed by article_id and number, so each
+// article's history is one item collection read back in order by a Query.
+
+func (db *Database) CreateRevision(revision *app.Revision) (*app.Revision, error) {
+	item, err := dynamodbattribute.MarshalMap(revision)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Client.PutItem(&dynamodb.PutItemInput{
+		Item:                item,
+		TableName:           aws.String(db.RevisionTablename),
+		ConditionExpression: aws.String("attribute_not_exists(article_id)"),
+	})
+	if err != nil {
+		if isConditionalCheckFailed(err) {
+			return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("revision %d of article [ %s ] already exists", revision.Number, revision.ArticleID))
+		}
+		return nil, err
+	}
+	return revision, nil
+}
+
+// UpdateArticleWithRevision puts the article and the revision in one
+// transaction, each under the condition its own write has on its own.
+func (db *Database) UpdateArticleWithRevision(article *app.Article, revision *app.Revision) (*app.Article, error) {
+	next := *article
+	next.Version++
+	articleItem, err := dynamodbattribute.MarshalMap(next)
+	if err != nil {
+		return nil, err
+	}
+	revisionItem, err := dynamodbattribute.MarshalMap(revision)
+	if err != nil {
+		return nil, err
+	}
+	expr, err := expression.NewBuilder().WithCondition(atVersion(article.Version)).Build()
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{Put: &dynamodb.Put{
+				Item:                      articleItem,
+				TableName:                 aws.String(db.ArticleTablename),
+				ConditionExpression:       expr.Condition(),
+				ExpressionAttributeNames:  expr.Names(),
+				ExpressionAttributeValues: expr.Values(),
+			}},
+			{Put: &dynamodb.Put{
+				Item:                revisionItem,
+				TableName:           aws.String(db.RevisionTablename),
+				ConditionExpression: aws.String("attribute_not_exists(article_id)"),
+			}},
+		},
+	})
+	switch failedCondition(err) {
+	case 0:
+		if _, readErr := db.ReadArticle(article.Id); readErr != nil {
+			return nil, readErr
+		}
+		return nil, errs.Wrap(app.ErrVersionMismatch, fmt.Sprintf("Article with id [ %s ] is not at version %d", article.Id, article.Version))
+	case 1:
+		return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("revision %d of article [ %s ] already exists", revision.Number, revision.ArticleID))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &next, nil
+}
+
+func (db *Database) ReadRevision(articleID string, number int) (*app.Revision, error) {
+	result, err := db.Client.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(db.RevisionTablename),
+		Key:       revisionKey(articleID, number),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, errs.Wrap(app.ErrNotFound, fmt.Sprintf("revision %d of article [ %s ] not found", number, articleID))
+	}
+	var revision app.Revision
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &revision); err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+func (db *Database) ReadRevisions(articleID string) ([]*app.Revision, error) {
+	revisions := []*app.Revision{}
+	expr, err := expression.NewBuilder().
+		WithKeyCondition(expression.Key("article_id").Equal(expression.Value(articleID))).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(db.RevisionTablename),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+	var unmarshalErr error
+	err = db.Client.QueryPages(input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		var items []*app.Revision
+		if unmarshalErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &items); unmarshalErr != nil {
+			return false
+		}
+		revisions = append(revisions, items...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if unmarshalErr != nil {
+		return nil, unmarshalErr
+	}
+	return revisions, nil
+}
+
+// DeleteRevisions removes an article's history one revision at a time;
+// there is no way to delete an item collection as a whole.
+func (db *Database) DeleteRevisions(articleID string) error {
+	revisions, err := db.ReadRevisions(articleID)
+	if err != nil {
+		return err
+	}
+	for _, revision := range revisions {
+		_, err := db.Client.DeleteItem(&dynamodb.DeleteItemInput{
+			TableName: aws.String(db.RevisionTablename),
+			Key:       revisionKey(articleID, revision.Number),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func revisionKey(articleID string, number int) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"article_id": {S: aws.String(articleID)},
+		"number":     {N: aws.String(strconv.Itoa(number))},
+	}
+}
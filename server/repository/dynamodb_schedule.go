@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"fmt"
+
+	app "example.com/server/app"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	errs "github.com/pkg/errors"
+)
+
+// ReadDueArticles queries the draft and in_review partitions of the status
+// index for articles whose publish_at has passed.
+func (db *Database) ReadDueArticles(now int64, limit int) ([]*app.Article, error) {
+	articles := []*app.Article{}
+	for _, status := range []app.ArticleStatus{app.StatusDraft, app.StatusInReview} {
+		expr, err := expression.NewBuilder().
+			WithKeyCondition(expression.Key("status").Equal(expression.Value(status))).
+			WithFilter(expression.Name("publish_at").Between(expression.Value(1), expression.Value(now))).
+			Build()
+		if err != nil {
+			return nil, err
+		}
+		input := &dynamodb.QueryInput{
+			TableName:                 aws.String(db.ArticleTablename),
+			IndexName:                 aws.String(articlesByStatusIndex),
+			KeyConditionExpression:    expr.KeyCondition(),
+			FilterExpression:          expr.Filter(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		}
+		for len(articles) < limit {
+			result, err := db.Client.Query(input)
+			if err != nil {
+				return nil, err
+			}
+			var page []*app.Article
+			if err := dynamodbattribute.UnmarshalListOfMaps(result.Items, &page); err != nil {
+				return nil, err
+			}
+			articles = append(articles, page...)
+			if len(result.LastEvaluatedKey) == 0 {
+				break
+			}
+			input.ExclusiveStartKey = result.LastEvaluatedKey
+		}
+	}
+	if len(articles) > limit {
+		articles = articles[:limit]
+	}
+	return articles, nil
+}
+
+// PublishScheduledArticle publishes the article with a write conditioned on
+// it still being unpublished and scheduled for publishAt. DynamoDB applies
+// the condition and the write atomically, so of several replicas racing for
+// the same article exactly one succeeds.
+func (db *Database) PublishScheduledArticle(id string, publishAt, publishedAt int64) (*app.Article, error) {
+	cond := expression.Name("publish_at").Equal(expression.Value(publishAt)).
+		And(expression.Name("status").In(expression.Value(app.StatusDraft), expression.Value(app.StatusInReview)))
+	update := expression.Set(expression.Name("status"), expression.Value(app.StatusPublished)).
+		Set(expression.Name("published_at"), expression.Value(publishedAt)).
+		Set(expression.Name("publish_at"), expression.Value(0)).
+		Set(expression.Name("version"), expression.Plus(expression.Name("version").IfNotExists(expression.Value(0)), expression.Value(1)))
+	expr, err := expression.NewBuilder().WithCondition(cond).WithUpdate(update).Build()
+	if err != nil {
+		return nil, err
+	}
+	result, err := db.Client.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(db.ArticleTablename),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {S: aws.String(id)},
+		},
+		ConditionExpression:       expr.Condition(),
+		UpdateExpression:          expr.Update(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+	})
+	if err != nil {
+		if isConditionalCheckFailed(err) {
+			return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("article with id [ %s ] no longer scheduled for %d", id, publishAt))
+		}
+		return nil, err
+	}
+	var article app.Article
+	if err := dynamodbattribute.UnmarshalMap(result.Attributes, &article); err != nil {
+		return nil, err
+	}
+	return &article, nil
+}
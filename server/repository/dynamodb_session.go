@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"fmt"
+
+	app "example.com/server/app"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	errs "github.com/pkg/errors"
+)
+
+func (db *Database) CreateSession(session *app.Session) (*app.Session, error) {
+	return db.putSession(session)
+}
+
+func (db *Database) ReadSession(id string) (*app.Session, error) {
+	result, err := db.Client.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(db.SessionTablename),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	})
+	if err != nil {
+		return &app.Session{}, err
+	}
+	if result.Item == nil {
+		msg := fmt.Sprintf("Session with id [ %s ] not found", id)
+		return &app.Session{}, errs.Wrap(app.ErrNotFound, msg)
+	}
+	var session app.Session
+	err = dynamodbattribute.UnmarshalMap(result.Item, &session)
+	if err != nil {
+		return &app.Session{}, err
+	}
+
+	return &session, nil
+}
+
+func (db *Database) ReadSessions(authorID string) ([]*app.Session, error) {
+	sessions := []*app.Session{}
+	filt := expression.Name("author_id").Equal(expression.Value(authorID))
+	expr, err := expression.NewBuilder().WithFilter(filt).Build()
+	if err != nil {
+		return sessions, err
+	}
+	params := &dynamodb.ScanInput{
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		FilterExpression:          expr.Filter(),
+		TableName:                 aws.String(db.SessionTablename),
+	}
+	var unmarshalErr error
+	err = db.Client.ScanPages(params, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			var session app.Session
+			if unmarshalErr = dynamodbattribute.UnmarshalMap(item, &session); unmarshalErr != nil {
+				return false
+			}
+			sessions = append(sessions, &session)
+		}
+		return true
+	})
+	if err != nil {
+		return []*app.Session{}, err
+	}
+	if unmarshalErr != nil {
+		return []*app.Session{}, unmarshalErr
+	}
+
+	return sessions, nil
+}
+
+func (db *Database) UpdateSession(session *app.Session) (*app.Session, error) {
+	return db.putSession(session)
+}
+
+func (db *Database) putSession(session *app.Session) (*app.Session, error) {
+	entityParsed, err := dynamodbattribute.MarshalMap(session)
+	if err != nil {
+		return &app.Session{}, err
+	}
+
+	input := &dynamodb.PutItemInput{
+		Item:      entityParsed,
+		TableName: aws.String(db.SessionTablename),
+	}
+
+	_, err = db.Client.PutItem(input)
+	if err != nil {
+		return &app.Session{}, err
+	}
+
+	return session, nil
+}
+
+// RotateSession conditions the write on the stored token hash, which
+// DynamoDB checks atomically with it.
+func (db *Database) RotateSession(session *app.Session, previousHash string) (*app.Session, error) {
+	cond := expression.Name("token_hash").Equal(expression.Value(previousHash)).
+		And(expression.Name("revoked_at").Equal(expression.Value(0)))
+	update := expression.Set(expression.Name("token_hash"), expression.Value(session.TokenHash)).
+		Set(expression.Name("last_used_at"), expression.Value(session.LastUsedAt))
+	expr, err := expression.NewBuilder().WithCondition(cond).WithUpdate(update).Build()
+	if err != nil {
+		return nil, err
+	}
+	result, err := db.Client.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(db.SessionTablename),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {S: aws.String(session.Id)},
+		},
+		ConditionExpression:       expr.Condition(),
+		UpdateExpression:          expr.Update(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+	})
+	if err != nil {
+		if isConditionalCheckFailed(err) {
+			return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("Session with id [ %s ] was rotated or revoked", session.Id))
+		}
+		return nil, err
+	}
+	var rotated app.Session
+	if err := dynamodbattribute.UnmarshalMap(result.Attributes, &rotated); err != nil {
+		return nil, err
+	}
+	return &rotated, nil
+}
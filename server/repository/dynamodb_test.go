@@ -0,0 +1,144 @@
+package repository_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	app "example.com/server/app"
+	"example.com/server/repository"
+	"example.com/server/repository/repotest"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/google/uuid"
+)
+
+// TestDynamoDB runs against the DynamoDB Local at DYNAMODB_ENDPOINT, in
+// tables it creates for the run and deletes afterwards, and is skipped
+// without one. CI starts amazon/dynamodb-local for it.
+func TestDynamoDB(t *testing.T) {
+	endpoint := os.Getenv("DYNAMODB_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("DYNAMODB_ENDPOINT is not set")
+	}
+	// DynamoDB Local accepts any credentials, but the SDK wants some.
+	for name, value := range map[string]string{
+		"AWS_REGION":            "eu-central-1",
+		"AWS_ACCESS_KEY_ID":     "local",
+		"AWS_SECRET_ACCESS_KEY": "local",
+	} {
+		if os.Getenv(name) == "" {
+			t.Setenv(name, value)
+		}
+	}
+	prefix := "test-" + uuid.New().String()[:8] + "-"
+	cfg := repository.DynamoDBConfig{
+		Endpoint:       endpoint,
+		UsersTable:     prefix + "users",
+		ArticlesTable:  prefix + "articles",
+		SessionsTable:  prefix + "sessions",
+		APIKeysTable:   prefix + "api_keys",
+		RevisionsTable: prefix + "revisions",
+		AttemptsTable:  prefix + "attempts",
+		EmailsTable:    prefix + "emails",
+	}
+	db, err := repository.InitDynamoDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range dynamoTables(cfg) {
+		createTable(t, db.Client, table)
+	}
+	repotest.Run(t, func(t *testing.T) app.AppRepository { return db })
+}
+
+// dynamoTables describes the tables and indexes the server expects to have
+// been provisioned.
+func dynamoTables(cfg repository.DynamoDBConfig) []*dynamodb.CreateTableInput {
+	byID := func(name string) *dynamodb.CreateTableInput {
+		return table(name, []string{"id", "S"}, nil)
+	}
+	users := byID(cfg.UsersTable)
+	addIndex(users, "email_lower-index", []string{"email_lower", "S"}, nil)
+	articles := byID(cfg.ArticlesTable)
+	addIndex(articles, "author_id-created_at-index", []string{"author_id", "S"}, []string{"created_at", "N"})
+	addIndex(articles, "author_id-rate-index", []string{"author_id", "S"}, []string{"rate", "N"})
+	addIndex(articles, "status-created_at-index", []string{"status", "S"}, []string{"created_at", "N"})
+	return []*dynamodb.CreateTableInput{
+		users,
+		articles,
+		byID(cfg.SessionsTable),
+		byID(cfg.APIKeysTable),
+		table(cfg.RevisionsTable, []string{"article_id", "S"}, []string{"number", "N"}),
+		table(cfg.AttemptsTable, []string{"key", "S"}, nil),
+		table(cfg.EmailsTable, []string{"email", "S"}, nil),
+	}
+}
+
+// table is an on-demand table keyed by hash and, unless it is nil, range;
+// each is an attribute name and type.
+func table(name string, hash, rangeKey []string) *dynamodb.CreateTableInput {
+	input := &dynamodb.CreateTableInput{
+		TableName:   aws.String(name),
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+	}
+	input.KeySchema = keySchema(input, hash, rangeKey)
+	return input
+}
+
+func addIndex(input *dynamodb.CreateTableInput, name string, hash, rangeKey []string) {
+	input.GlobalSecondaryIndexes = append(input.GlobalSecondaryIndexes, &dynamodb.GlobalSecondaryIndex{
+		IndexName:  aws.String(name),
+		KeySchema:  keySchema(input, hash, rangeKey),
+		Projection: &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeAll)},
+	})
+}
+
+// keySchema returns the key schema for hash and rangeKey, declaring their
+// attributes on input.
+func keySchema(input *dynamodb.CreateTableInput, hash, rangeKey []string) []*dynamodb.KeySchemaElement {
+	schema := []*dynamodb.KeySchemaElement{}
+	for _, key := range []struct {
+		attribute []string
+		keyType   string
+	}{
+		{hash, dynamodb.KeyTypeHash},
+		{rangeKey, dynamodb.KeyTypeRange},
+	} {
+		if key.attribute == nil {
+			continue
+		}
+		schema = append(schema, &dynamodb.KeySchemaElement{
+			AttributeName: aws.String(key.attribute[0]),
+			KeyType:       aws.String(key.keyType),
+		})
+		declared := false
+		for _, definition := range input.AttributeDefinitions {
+			declared = declared || *definition.AttributeName == key.attribute[0]
+		}
+		if !declared {
+			input.AttributeDefinitions = append(input.AttributeDefinitions, &dynamodb.AttributeDefinition{
+				AttributeName: aws.String(key.attribute[0]),
+				AttributeType: aws.String(key.attribute[1]),
+			})
+		}
+	}
+	return schema
+}
+
+// createTable creates input's table, waits for it and deletes it when t ends.
+func createTable(t *testing.T, client *dynamodb.DynamoDB, input *dynamodb.CreateTableInput) {
+	t.Helper()
+	if _, err := client.CreateTable(input); err != nil {
+		t.Fatalf("creating %s: %v", *input.TableName, err)
+	}
+	t.Cleanup(func() {
+		if _, err := client.DeleteTable(&dynamodb.DeleteTableInput{TableName: input.TableName}); err != nil && !strings.Contains(err.Error(), dynamodb.ErrCodeResourceNotFoundException) {
+			t.Errorf("deleting %s: %v", *input.TableName, err)
+		}
+	})
+	if err := client.WaitUntilTableExists(&dynamodb.DescribeTableInput{TableName: input.TableName}); err != nil {
+		t.Fatalf("waiting for %s: %v", *input.TableName, err)
+	}
+}
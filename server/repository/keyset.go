@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"strings"
+
+	app "example.com/server/app"
+)
+
+// sortColumn is one column of an ORDER BY.
+type sortColumn struct {
+	name string
+	desc bool
+}
+
+// articleColumns maps sortable article fields onto their SQL columns.
+var articleColumns = map[string]string{
+	app.SortCreatedAt: "create_at",
+	app.SortRate:      "rate",
+}
+
+// articleOrder is the SQL order for keys, newest first when keys is empty,
+// with the ID appended so rows never tie.
+func articleOrder(keys []app.SortKey) []sortColumn {
+	if len(keys) == 0 {
+		keys = []app.SortKey{{Field: app.SortCreatedAt, Desc: true}}
+	}
+	columns := make([]sortColumn, 0, len(keys)+1)
+	for _, key := range keys {
+		columns = append(columns, sortColumn{articleColumns[key.Field], key.Desc})
+	}
+	return append(columns, sortColumn{"id", keys[len(keys)-1].Desc})
+}
+
+func orderClause(columns []sortColumn) string {
+	parts := make([]string, len(columns))
+	for i, column := range columns {
+		parts[i] = column.name
+		if column.desc {
+			parts[i] += " desc"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// keysetCondition selects the rows that come after values in the order of
+// columns, e.g. "(a < ?) OR (a = ? AND b > ?)" for a descending, b ascending.
+// Row value comparison cannot express mixed directions, hence the expansion.
+func keysetCondition(columns []sortColumn, values []interface{}) (string, []interface{}) {
+	var (
+		clauses []string
+		args    []interface{}
+	)
+	for i, column := range columns {
+		var terms []string
+		for j := 0; j < i; j++ {
+			terms = append(terms, columns[j].name+" = ?")
+			args = append(args, values[j])
+		}
+		op := " > ?"
+		if column.desc {
+			op = " < ?"
+		}
+		terms = append(terms, column.name+op)
+		args = append(args, values[i])
+		clauses = append(clauses, "("+strings.Join(terms, " AND ")+")")
+	}
+	return strings.Join(clauses, " OR "), args
+}
+
+// articleCursor is the keyset position in an article listing. Sort records
+// the order it was issued for, since a position means nothing in another.
+type articleCursor struct {
+	Sort     string `json:"sort"`
+	CreateAt int64  `json:"created_at"`
+	Rate     int    `json:"rate"`
+	Id       string `json:"id"`
+}
+
+func newArticleCursor(keys []app.SortKey, article *app.Article) articleCursor {
+	return articleCursor{
+		Sort:     app.SortSpec(keys),
+		CreateAt: article.CreateAt,
+		Rate:     article.Rate,
+		Id:       article.Id,
+	}
+}
+
+// values lines the cursor up with the columns from articleOrder.
+func (c articleCursor) values(columns []sortColumn) []interface{} {
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		switch column.name {
+		case "create_at":
+			values[i] = c.CreateAt
+		case "rate":
+			values[i] = c.Rate
+		default:
+			values[i] = c.Id
+		}
+	}
+	return values
+}
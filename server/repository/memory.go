@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	app "example.com/server/app"
+
+	errs "github.com/pkg/errors"
+)
+
+type memorySessionRepository struct {
+	mu       sync.RWMutex
+	sessions map[string]app.Session
+}
+
+// NewMemorySessionRepository keeps sessions in process memory. Sessions are
+// lost on restart, which is acceptable for local development and tests.
+func NewMemorySessionRepository() app.SessionRepository {
+	return &memorySessionRepository{
+		sessions: map[string]app.Session{},
+	}
+}
+
+func (r *memorySessionRepository) CreateSession(session *app.Session) (*app.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.sessions[session.Id]; ok {
+		return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("session with ID :%s already exists", session.Id))
+	}
+	r.sessions[session.Id] = *session
+	return session, nil
+}
+
+func (r *memorySessionRepository) ReadSession(id string) (*app.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, errs.Wrap(app.ErrNotFound, fmt.Sprintf("session with ID :%s not found", id))
+	}
+	return &session, nil
+}
+
+func (r *memorySessionRepository) ReadSessions(authorID string) ([]*app.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	sessions := []*app.Session{}
+	for _, session := range r.sessions {
+		if session.AuthorID == authorID {
+			session := session
+			sessions = append(sessions, &session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreateAt > sessions[j].CreateAt
+	})
+	return sessions, nil
+}
+
+func (r *memorySessionRepository) UpdateSession(session *app.Session) (*app.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.sessions[session.Id]; !ok {
+		return nil, errs.Wrap(app.ErrNotFound, fmt.Sprintf("session with ID :%s not found", session.Id))
+	}
+	r.sessions[session.Id] = *session
+	return session, nil
+}
+
+func (r *memorySessionRepository) RotateSession(session *app.Session, previousHash string) (*app.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.sessions[session.Id]
+	if !ok {
+		return nil, errs.Wrap(app.ErrNotFound, fmt.Sprintf("session with ID :%s not found", session.Id))
+	}
+	if stored.TokenHash != previousHash || stored.RevokedAt != 0 {
+		return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("session with ID :%s was rotated or revoked", session.Id))
+	}
+	stored.TokenHash = session.TokenHash
+	stored.LastUsedAt = session.LastUsedAt
+	r.sessions[session.Id] = stored
+	return &stored, nil
+}
+
+type memoryAPIKeyRepository struct {
+	mu   sync.RWMutex
+	keys map[string]app.APIKey
+}
+
+// NewMemoryAPIKeyRepository keeps API keys in process memory.
+func NewMemoryAPIKeyRepository() app.APIKeyRepository {
+	return &memoryAPIKeyRepository{
+		keys: map[string]app.APIKey{},
+	}
+}
+
+func (r *memoryAPIKeyRepository) CreateAPIKey(key *app.APIKey) (*app.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.keys[key.Id]; ok {
+		return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("api key with ID :%s already exists", key.Id))
+	}
+	r.keys[key.Id] = *key
+	return key, nil
+}
+
+func (r *memoryAPIKeyRepository) ReadAPIKey(id string) (*app.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, errs.Wrap(app.ErrNotFound, fmt.Sprintf("api key with ID :%s not found", id))
+	}
+	return &key, nil
+}
+
+func (r *memoryAPIKeyRepository) ReadAPIKeys(authorID string) ([]*app.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	keys := []*app.APIKey{}
+	for _, key := range r.keys {
+		if key.AuthorID == authorID {
+			key := key
+			keys = append(keys, &key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreateAt > keys[j].CreateAt
+	})
+	return keys, nil
+}
+
+func (r *memoryAPIKeyRepository) UpdateAPIKey(key *app.APIKey) (*app.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.keys[key.Id]; !ok {
+		return nil, errs.Wrap(app.ErrNotFound, fmt.Sprintf("api key with ID :%s not found", key.Id))
+	}
+	r.keys[key.Id] = *key
+	return key, nil
+}
+
+func (r *memoryAPIKeyRepository) TouchAPIKey(id string, usedAt int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, ok := r.keys[id]
+	if !ok || key.RevokedAt != 0 {
+		return errs.Wrap(app.ErrNotFound, fmt.Sprintf("unrevoked api key with ID :%s not found", id))
+	}
+	key.LastUsedAt = usedAt
+	r.keys[id] = key
+	return nil
+}
+
+type memoryAttemptRepository struct {
+	mu       sync.Mutex
+	attempts map[string]app.LoginAttempts
+}
+
+// NewMemoryAttemptRepository keeps login throttling state in process memory,
+// so each server replica throttles independently. Audit entries are only
+// logged.
+func NewMemoryAttemptRepository() app.AttemptRepository {
+	return &memoryAttemptRepository{
+		attempts: map[string]app.LoginAttempts{},
+	}
+}
+
+func (r *memoryAttemptRepository) ReadAttempts(key string) (*app.LoginAttempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	attempts, ok := r.attempts[key]
+	if !ok {
+		return &app.LoginAttempts{Key: key}, nil
+	}
+	return &attempts, nil
+}
+
+func (r *memoryAttemptRepository) RecordFailure(key string, now, since int64, threshold int, lockUntil int64) (*app.LoginAttempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	attempts := r.attempts[key]
+	if attempts.LastFailure < since {
+		attempts = app.LoginAttempts{}
+	}
+	attempts.Key = key
+	attempts.Failures++
+	attempts.LastFailure = now
+	if attempts.Failures >= threshold && attempts.LockedUntil <= now {
+		attempts.LockedUntil = lockUntil
+	}
+	r.attempts[key] = attempts
+	return &attempts, nil
+}
+
+func (r *memoryAttemptRepository) DeleteAttempts(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.attempts, key)
+	return nil
+}
+
+func (r *memoryAttemptRepository) CreateAuditEntry(entry *app.AuditEntry) error {
+	log.Printf("audit: %s subject=%q ip=%s reason=%s", entry.Event, entry.Subject, entry.IP, entry.Reason)
+	return nil
+}
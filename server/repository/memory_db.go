@@ -0,0 +1,427 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	app "example.com/server/app"
+
+	"github.com/google/uuid"
+	errs "github.com/pkg/errors"
+)
+
+// MemoryDB keeps authors, articles and their revisions in process memory,
+// with the same ordering, paging and errors as the database backends, so the
+// API can run with no external services. Sessions, API keys, login attempts
+// and the search index are kept by the memory repositories for them.
+type MemoryDB struct {
+	app.SessionRepository
+	app.APIKeyRepository
+	app.AttemptRepository
+	app.SearchIndex
+
+	mu        sync.RWMutex
+	authors   map[string]app.Author
+	articles  map[string]app.Article
+	revisions map[string][]app.Revision
+}
+
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{
+		SessionRepository: NewMemorySessionRepository(),
+		APIKeyRepository:  NewMemoryAPIKeyRepository(),
+		AttemptRepository: NewMemoryAttemptRepository(),
+		SearchIndex:       NewMemorySearchIndex(),
+		authors:           map[string]app.Author{},
+		articles:          map[string]app.Article{},
+		revisions:         map[string][]app.Revision{},
+	}
+}
+
+func (db *MemoryDB) CreateAuthor(author *app.Author) (*app.Author, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	author.Id = uuid.New().String()
+	author.Version = 1
+	if _, ok := db.authors[author.Id]; ok {
+		return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("author with ID :%s already exists", author.Id))
+	}
+	if db.authorTaken(author) {
+		return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("author with email :%s or its identity already exists", author.Email))
+	}
+	db.authors[author.Id] = *author
+	return author, nil
+}
+
+// authorTaken reports whether another author has author's email, in any
+// case, or external identity, which the SQL backends' unique indexes reject.
+// The caller holds the lock.
+func (db *MemoryDB) authorTaken(author *app.Author) bool {
+	for id, other := range db.authors {
+		if id == author.Id {
+			continue
+		}
+		if strings.EqualFold(other.Email, author.Email) {
+			return true
+		}
+		if author.IdentitySubject != "" && other.IdentityIssuer == author.IdentityIssuer && other.IdentitySubject == author.IdentitySubject {
+			return true
+		}
+	}
+	return false
+}
+
+func (db *MemoryDB) ReadAuthor(id string) (*app.Author, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	author, ok := db.authors[id]
+	if !ok {
+		return nil, errs.Wrap(app.ErrNotFound, fmt.Sprintf("author with ID :%s not found", id))
+	}
+	return &author, nil
+}
+
+func (db *MemoryDB) ReadAuthorByEmail(email string) (*app.Author, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	for _, author := range db.authors {
+		if strings.EqualFold(author.Email, email) {
+			return &author, nil
+		}
+	}
+	return nil, errs.Wrap(app.ErrNotFound, fmt.Sprintf("author with email :%s not found", email))
+}
+
+// ReadAuthors pages through authors in ID order, like the Postgres backend.
+func (db *MemoryDB) ReadAuthors(page app.PageRequest) (*app.AuthorPage, error) {
+	var after idCursor
+	if page.Cursor != "" {
+		if err := decodeCursor(page.Cursor, &after); err != nil {
+			return nil, err
+		}
+	}
+	db.mu.RLock()
+	authors := []*app.Author{}
+	for _, author := range db.authors {
+		if author.Id > after.Id {
+			author := author
+			authors = append(authors, &author)
+		}
+	}
+	db.mu.RUnlock()
+	sort.Slice(authors, func(i, j int) bool {
+		return authors[i].Id < authors[j].Id
+	})
+	result := &app.AuthorPage{Authors: authors}
+	if len(authors) > page.Limit {
+		result.Authors = authors[:page.Limit]
+		cursor, err := encodeCursor(idCursor{Id: authors[page.Limit-1].Id})
+		if err != nil {
+			return nil, err
+		}
+		result.NextCursor = cursor
+	}
+	return result, nil
+}
+
+func (db *MemoryDB) UpdateAuthor(author *app.Author) (*app.Author, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	stored, ok := db.authors[author.Id]
+	if !ok {
+		return nil, errs.Wrap(app.ErrNotFound, fmt.Sprintf("author with ID :%s not found", author.Id))
+	}
+	if stored.Version != author.Version {
+		return nil, errs.Wrap(app.ErrVersionMismatch, fmt.Sprintf("author with ID: %s is not at version %d", author.Id, author.Version))
+	}
+	if db.authorTaken(author) {
+		return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("author with email :%s or its identity already exists", author.Email))
+	}
+	next := *author
+	next.Version++
+	db.authors[author.Id] = next
+	return &next, nil
+}
+
+func (db *MemoryDB) DeleteAuthor(id string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.authors[id]; !ok {
+		return errs.Wrap(app.ErrNotFound, fmt.Sprintf("author with ID: %s not found", id))
+	}
+	delete(db.authors, id)
+	return nil
+}
+
+func (db *MemoryDB) CreateArticle(article *app.Article) (*app.Article, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	article.Id = uuid.New().String()
+	article.Version = 1
+	if _, ok := db.articles[article.Id]; ok {
+		return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("article with ID :%s already exists", article.Id))
+	}
+	db.articles[article.Id] = *article
+	return article, nil
+}
+
+func (db *MemoryDB) ReadArticle(id string) (*app.Article, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	article, ok := db.articles[id]
+	if !ok {
+		return nil, errs.Wrap(app.ErrNotFound, fmt.Sprintf("article with ID :%s not found", id))
+	}
+	return &article, nil
+}
+
+// ReadArticles filters, sorts and pages like the Postgres backend, using the
+// same keyset cursors.
+func (db *MemoryDB) ReadArticles(query app.ArticleQuery, page app.PageRequest) (*app.ArticlePage, error) {
+	columns := articleOrder(query.Sort)
+	var after []interface{}
+	if page.Cursor != "" {
+		var cursor articleCursor
+		if err := decodeCursor(page.Cursor, &cursor); err != nil {
+			return nil, err
+		}
+		if cursor.Sort != app.SortSpec(query.Sort) {
+			return nil, errs.Wrap(app.ErrInvalidCursor, "cursor is for another sort order")
+		}
+		after = cursor.values(columns)
+	}
+	db.mu.RLock()
+	articles := []*app.Article{}
+	for _, article := range db.articles {
+		if !matchesQuery(&article, query) {
+			continue
+		}
+		if after != nil && compareKeys(columns, articleKeys(query.Sort, columns, &article), after) <= 0 {
+			continue
+		}
+		article := article
+		articles = append(articles, &article)
+	}
+	db.mu.RUnlock()
+	sort.Slice(articles, func(i, j int) bool {
+		return compareKeys(columns, articleKeys(query.Sort, columns, articles[i]), articleKeys(query.Sort, columns, articles[j])) < 0
+	})
+	result := &app.ArticlePage{Articles: articles}
+	if len(articles) > page.Limit {
+		result.Articles = articles[:page.Limit]
+		cursor, err := encodeCursor(newArticleCursor(query.Sort, articles[page.Limit-1]))
+		if err != nil {
+			return nil, err
+		}
+		result.NextCursor = cursor
+	}
+	return result, nil
+}
+
+func matchesQuery(article *app.Article, query app.ArticleQuery) bool {
+	if query.AuthorID != "" && article.AuthorID != query.AuthorID {
+		return false
+	}
+	if len(query.Statuses) > 0 && !hasStatus(query.Statuses, article.Status) {
+		return false
+	}
+	if article.Rate < query.MinRate {
+		return false
+	}
+	if query.Since != 0 && article.CreateAt < query.Since {
+		return false
+	}
+	if query.Until != 0 && article.CreateAt >= query.Until {
+		return false
+	}
+	return true
+}
+
+func hasStatus(statuses []app.ArticleStatus, status app.ArticleStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// articleKeys is article's position in the order of columns.
+func articleKeys(keys []app.SortKey, columns []sortColumn, article *app.Article) []interface{} {
+	return newArticleCursor(keys, article).values(columns)
+}
+
+// compareKeys orders two positions from articleCursor.values by columns.
+func compareKeys(columns []sortColumn, a, b []interface{}) int {
+	for i, column := range columns {
+		var c int
+		switch x := a[i].(type) {
+		case int64:
+			c = compareInts(x, b[i].(int64))
+		case int:
+			c = compareInts(int64(x), int64(b[i].(int)))
+		case string:
+			y := b[i].(string)
+			switch {
+			case x < y:
+				c = -1
+			case x > y:
+				c = 1
+			}
+		}
+		if column.desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareInts(x, y int64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func (db *MemoryDB) UpdateArticle(article *app.Article) (*app.Article, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	stored, ok := db.articles[article.Id]
+	if !ok {
+		return nil, errs.Wrap(app.ErrNotFound, fmt.Sprintf("article with ID :%s not found", article.Id))
+	}
+	if stored.Version != article.Version {
+		return nil, errs.Wrap(app.ErrVersionMismatch, fmt.Sprintf("article with ID: %s is not at version %d", article.Id, article.Version))
+	}
+	next := *article
+	next.Version++
+	db.articles[article.Id] = next
+	return &next, nil
+}
+
+func (db *MemoryDB) DeleteArticle(id string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.articles[id]; !ok {
+		return errs.Wrap(app.ErrNotFound, fmt.Sprintf("article with ID: %s not found", id))
+	}
+	delete(db.articles, id)
+	return nil
+}
+
+func (db *MemoryDB) CreateRevision(revision *app.Revision) (*app.Revision, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.createRevision(revision)
+}
+
+// UpdateArticleWithRevision checks both writes before making either, under
+// one lock.
+func (db *MemoryDB) UpdateArticleWithRevision(article *app.Article, revision *app.Revision) (*app.Article, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	stored, ok := db.articles[article.Id]
+	if !ok {
+		return nil, errs.Wrap(app.ErrNotFound, fmt.Sprintf("article with ID :%s not found", article.Id))
+	}
+	if stored.Version != article.Version {
+		return nil, errs.Wrap(app.ErrVersionMismatch, fmt.Sprintf("article with ID: %s is not at version %d", article.Id, article.Version))
+	}
+	if _, err := db.createRevision(revision); err != nil {
+		return nil, err
+	}
+	next := *article
+	next.Version++
+	db.articles[article.Id] = next
+	return &next, nil
+}
+
+// createRevision inserts revision in number order; db.mu must be held for
+// writing.
+func (db *MemoryDB) createRevision(revision *app.Revision) (*app.Revision, error) {
+	revisions := db.revisions[revision.ArticleID]
+	i := sort.Search(len(revisions), func(i int) bool {
+		return revisions[i].Number >= revision.Number
+	})
+	if i < len(revisions) && revisions[i].Number == revision.Number {
+		return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("revision %d of article %s already exists", revision.Number, revision.ArticleID))
+	}
+	revisions = append(revisions, app.Revision{})
+	copy(revisions[i+1:], revisions[i:])
+	revisions[i] = *revision
+	db.revisions[revision.ArticleID] = revisions
+	return revision, nil
+}
+
+func (db *MemoryDB) ReadRevision(articleID string, number int) (*app.Revision, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	for _, revision := range db.revisions[articleID] {
+		if revision.Number == number {
+			return &revision, nil
+		}
+	}
+	return nil, errs.Wrap(app.ErrNotFound, fmt.Sprintf("revision %d of article %s not found", number, articleID))
+}
+
+func (db *MemoryDB) ReadRevisions(articleID string) ([]*app.Revision, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	revisions := []*app.Revision{}
+	for _, revision := range db.revisions[articleID] {
+		revision := revision
+		revisions = append(revisions, &revision)
+	}
+	return revisions, nil
+}
+
+func (db *MemoryDB) DeleteRevisions(articleID string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	delete(db.revisions, articleID)
+	return nil
+}
+
+func (db *MemoryDB) ReadDueArticles(now int64, limit int) ([]*app.Article, error) {
+	db.mu.RLock()
+	articles := []*app.Article{}
+	for _, article := range db.articles {
+		if hasStatus(editableStatuses, article.Status) && article.PublishAt > 0 && article.PublishAt <= now {
+			article := article
+			articles = append(articles, &article)
+		}
+	}
+	db.mu.RUnlock()
+	sort.Slice(articles, func(i, j int) bool {
+		return articles[i].PublishAt < articles[j].PublishAt
+	})
+	if len(articles) > limit {
+		articles = articles[:limit]
+	}
+	return articles, nil
+}
+
+// PublishScheduledArticle checks and publishes under one lock, which is the
+// compare-and-set the interface asks for within a single process.
+func (db *MemoryDB) PublishScheduledArticle(id string, publishAt, publishedAt int64) (*app.Article, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	article, ok := db.articles[id]
+	if !ok || article.PublishAt != publishAt || !hasStatus(editableStatuses, article.Status) {
+		return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("article with ID: %s no longer scheduled for %d", id, publishAt))
+	}
+	article.Status = app.StatusPublished
+	article.PublishedAt = publishedAt
+	article.PublishAt = 0
+	article.Version++
+	db.articles[id] = article
+	return &article, nil
+}
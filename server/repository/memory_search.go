@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"html"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	app "example.com/server/app"
+)
+
+// Okapi BM25 parameters, with title words counting titleWeight times as
+// much as body words.
+const (
+	bm25K1      = 1.2
+	bm25B       = 0.75
+	titleWeight = 2.0
+)
+
+// snippetWords is how many words of context a snippet shows.
+const snippetWords = 30
+
+var wordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+type indexedArticle struct {
+	article app.Article
+	// terms holds weighted term frequencies and length their sum.
+	terms  map[string]float64
+	length float64
+}
+
+type memorySearchIndex struct {
+	mu          sync.RWMutex
+	docs        map[string]*indexedArticle
+	postings    map[string]map[string]struct{}
+	totalLength float64
+}
+
+// NewMemorySearchIndex is an inverted index held in process memory, for
+// backends without full-text search of their own. It starts empty; fill it
+// with app.RebuildIndex.
+func NewMemorySearchIndex() app.SearchIndex {
+	return &memorySearchIndex{
+		docs:     map[string]*indexedArticle{},
+		postings: map[string]map[string]struct{}{},
+	}
+}
+
+func (idx *memorySearchIndex) IndexArticle(article *app.Article) error {
+	doc := &indexedArticle{article: *article, terms: map[string]float64{}}
+	for _, term := range tokenize(article.Title) {
+		doc.terms[term] += titleWeight
+	}
+	for _, term := range tokenize(article.Body) {
+		doc.terms[term]++
+	}
+	for _, tf := range doc.terms {
+		doc.length += tf
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(article.Id)
+	idx.docs[article.Id] = doc
+	idx.totalLength += doc.length
+	for term := range doc.terms {
+		if idx.postings[term] == nil {
+			idx.postings[term] = map[string]struct{}{}
+		}
+		idx.postings[term][article.Id] = struct{}{}
+	}
+	return nil
+}
+
+func (idx *memorySearchIndex) RemoveArticle(id string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(id)
+	return nil
+}
+
+// remove drops id from the index; idx.mu must be held for writing.
+func (idx *memorySearchIndex) remove(id string) {
+	doc, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for term := range doc.terms {
+		delete(idx.postings[term], id)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	idx.totalLength -= doc.length
+	delete(idx.docs, id)
+}
+
+func (idx *memorySearchIndex) SearchArticles(query string, limit int) ([]*app.SearchHit, error) {
+	terms := map[string]bool{}
+	for _, term := range tokenize(query) {
+		terms[term] = true
+	}
+	hits := []*app.SearchHit{}
+	if len(terms) == 0 {
+		return hits, nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	// A hit has every term, so the postings of the rarest one hold them all.
+	var candidates map[string]struct{}
+	for term := range terms {
+		postings, ok := idx.postings[term]
+		if !ok {
+			return hits, nil
+		}
+		if candidates == nil || len(postings) < len(candidates) {
+			candidates = postings
+		}
+	}
+	n := float64(len(idx.docs))
+	avgLength := idx.totalLength / n
+	for id := range candidates {
+		doc := idx.docs[id]
+		score := 0.0
+		for term := range terms {
+			tf, ok := doc.terms[term]
+			if !ok {
+				score = -1
+				break
+			}
+			df := float64(len(idx.postings[term]))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*doc.length/avgLength))
+		}
+		if score < 0 {
+			continue
+		}
+		article := doc.article
+		hits = append(hits, &app.SearchHit{
+			Article: &article,
+			Score:   score,
+		})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Article.CreateAt > hits[j].Article.CreateAt
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	for _, hit := range hits {
+		hit.Snippet = snippet(hit.Article, terms)
+	}
+	return hits, nil
+}
+
+func tokenize(text string) []string {
+	words := wordPattern.FindAllString(text, -1)
+	for i, word := range words {
+		words[i] = strings.ToLower(word)
+	}
+	return words
+}
+
+// snippet excerpts the body around its first matching word, or the title when
+// only the title matches, escaping it for HTML and marking every match.
+func snippet(article *app.Article, terms map[string]bool) string {
+	text := article.Body
+	spans := wordPattern.FindAllStringIndex(text, -1)
+	first := matchingSpan(text, spans, terms)
+	if first < 0 {
+		text = article.Title
+		spans = wordPattern.FindAllStringIndex(text, -1)
+		first = 0
+	}
+	if len(spans) == 0 {
+		return html.EscapeString(text)
+	}
+	start := first - snippetWords/4
+	if start < 0 {
+		start = 0
+	}
+	end := start + snippetWords
+	if end > len(spans) {
+		end = len(spans)
+	}
+
+	var b strings.Builder
+	pos := 0
+	if start > 0 {
+		b.WriteString("…")
+		pos = spans[start][0]
+	}
+	for _, span := range spans[start:end] {
+		b.WriteString(html.EscapeString(text[pos:span[0]]))
+		word := html.EscapeString(text[span[0]:span[1]])
+		if terms[strings.ToLower(text[span[0]:span[1]])] {
+			word = "<mark>" + word + "</mark>"
+		}
+		b.WriteString(word)
+		pos = span[1]
+	}
+	if end < len(spans) {
+		b.WriteString("…")
+	} else {
+		b.WriteString(html.EscapeString(text[pos:]))
+	}
+	return b.String()
+}
+
+func matchingSpan(text string, spans [][]int, terms map[string]bool) int {
+	for i, span := range spans {
+		if terms[strings.ToLower(text[span[0]:span[1]])] {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,15 @@
+package repository_test
+
+import (
+	"testing"
+
+	app "example.com/server/app"
+	"example.com/server/repository"
+	"example.com/server/repository/repotest"
+)
+
+func TestMemoryDB(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) app.AppRepository {
+		return repository.NewMemoryDB()
+	})
+}
@@ -0,0 +1,278 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
+)
+
+// ErrSchemaOutdated is returned when opening a database whose schema is
+// behind the migrations this build carries.
+var ErrSchemaOutdated = errors.New("database schema is out of date")
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrationFile matches e.g. 0002_text_columns.up.sql.
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one step of a backend's schema, numbered from 1. Checksum is
+// of its up SQL, which must not change once it has been applied anywhere.
+type Migration struct {
+	Version  int
+	Name     string
+	Checksum string
+	up, down string
+}
+
+// MigrationStatus is a migration as recorded in the database.
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt int64
+}
+
+// migrationRecord is a row of the migrations table.
+type migrationRecord struct {
+	Version   int
+	Name      string
+	Checksum  string
+	AppliedAt int64
+}
+
+func (migrationRecord) TableName() string {
+	return "migrations"
+}
+
+// Migrator applies the embedded migrations of one SQL backend.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+func newMigrator(db *gorm.DB, dialect string) (*Migrator, error) {
+	migrations, err := loadMigrations(dialect)
+	if err != nil {
+		return nil, err
+	}
+	res := db.Exec(`CREATE TABLE IF NOT EXISTS migrations (
+		version integer PRIMARY KEY,
+		name text NOT NULL,
+		checksum text NOT NULL,
+		applied_at bigint NOT NULL
+	)`)
+	if res.Error != nil {
+		return nil, errs.Wrap(res.Error, "creating migrations table")
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// NewPostgresMigrator opens the Postgres database for migrating, whatever
+// state its schema is in.
+func NewPostgresMigrator(cfg PostgresConfig) (*Migrator, error) {
+	db, err := openPostgres(cfg)
+	if err != nil {
+		return nil, errs.Wrap(err, "connecting to Postgres")
+	}
+	migrator, err := newMigrator(db, "postgres")
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return migrator, nil
+}
+
+// NewSQLiteMigrator opens the SQLite database for migrating, whatever state
+// its schema is in.
+func NewSQLiteMigrator(cfg SQLiteConfig) (*Migrator, error) {
+	db, err := openSQLite(cfg)
+	if err != nil {
+		return nil, errs.Wrap(err, "opening SQLite")
+	}
+	migrator, err := newMigrator(db, "sqlite")
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return migrator, nil
+}
+
+// loadMigrations reads migrations/<dialect>, which must hold an up and a
+// down file for every version from 1 up to the latest.
+func loadMigrations(dialect string) ([]Migration, error) {
+	dir := "migrations/" + dialect
+	entries, err := migrationFiles.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%s/%s is not named like 0001_name.up.sql", dir, entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		data, err := migrationFiles.ReadFile(dir + "/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		migration := byVersion[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if match[3] == "up" {
+			migration.up = string(data)
+		} else {
+			migration.down = string(data)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for version := 1; version <= len(byVersion); version++ {
+		migration := byVersion[version]
+		if migration == nil || migration.up == "" || migration.down == "" {
+			return nil, fmt.Errorf("%s: migration %d is missing its up or down file", dir, version)
+		}
+		sum := sha256.Sum256([]byte(migration.up))
+		migration.Checksum = hex.EncodeToString(sum[:])
+		migrations = append(migrations, *migration)
+	}
+	return migrations, nil
+}
+
+// checkSchema fails unless db has every migration of dialect applied.
+func checkSchema(db *gorm.DB, dialect string) error {
+	migrator, err := newMigrator(db, dialect)
+	if err != nil {
+		return err
+	}
+	return migrator.Check()
+}
+
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// applied reads the migrations table and checks it against the embedded
+// migrations: they must be a prefix of them, unchanged since they were
+// applied.
+func (m *Migrator) applied() ([]migrationRecord, error) {
+	var records []migrationRecord
+	if res := m.db.Order("version").Find(&records); res.Error != nil {
+		return nil, errs.Wrap(res.Error, "reading migrations")
+	}
+	for i, record := range records {
+		if record.Version > len(m.migrations) {
+			return nil, fmt.Errorf("database has migration %d, newer than the %d this build knows", record.Version, len(m.migrations))
+		}
+		if record.Version != i+1 {
+			return nil, fmt.Errorf("database has migration %d but not %d", record.Version, i+1)
+		}
+		if migration := m.migrations[i]; record.Checksum != migration.Checksum {
+			return nil, fmt.Errorf("migration %04d_%s was changed after it was applied", migration.Version, migration.Name)
+		}
+	}
+	return records, nil
+}
+
+// Check returns ErrSchemaOutdated unless every migration has been applied.
+func (m *Migrator) Check() error {
+	records, err := m.applied()
+	if err != nil {
+		return err
+	}
+	if len(records) < len(m.migrations) {
+		return errs.Wrapf(ErrSchemaOutdated, "at version %d of %d, run `modart migrate up`", len(records), len(m.migrations))
+	}
+	return nil
+}
+
+// Up applies every pending migration in order, each in a transaction of its
+// own, and returns those it applied.
+func (m *Migrator) Up() ([]Migration, error) {
+	records, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, migration := range m.migrations[len(records):] {
+		record := migrationRecord{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Checksum:  migration.Checksum,
+			AppliedAt: time.Now().UTC().Unix(),
+		}
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := execSQL(tx, migration.up); err != nil {
+				return err
+			}
+			return tx.Create(&record).Error
+		})
+		if err != nil {
+			return done, errs.Wrapf(err, "applying migration %04d_%s", migration.Version, migration.Name)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down reverts the latest applied migration and returns it, or nil when none
+// has been applied.
+func (m *Migrator) Down() (*Migration, error) {
+	records, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	migration := m.migrations[len(records)-1]
+	err = m.db.Transaction(func(tx *gorm.DB) error {
+		if err := execSQL(tx, migration.down); err != nil {
+			return err
+		}
+		return tx.Delete(migrationRecord{}, "version = ?", migration.Version).Error
+	})
+	if err != nil {
+		return nil, errs.Wrapf(err, "reverting migration %04d_%s", migration.Version, migration.Name)
+	}
+	return &migration, nil
+}
+
+// execSQL runs the statements of a migration file. Files of comments alone
+// are skipped; drivers disagree on what running no statements means.
+func execSQL(tx *gorm.DB, sql string) error {
+	for _, line := range strings.Split(sql, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "--") {
+			return tx.Exec(sql).Error
+		}
+	}
+	return nil
+}
+
+// Status lists every migration this build knows, oldest first, with whether
+// and when it was applied.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	records, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i].Migration = migration
+		if i < len(records) {
+			statuses[i].Applied = true
+			statuses[i].AppliedAt = records[i].AppliedAt
+		}
+	}
+	return statuses, nil
+}
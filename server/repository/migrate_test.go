@@ -0,0 +1,142 @@
+package repository_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	app "example.com/server/app"
+	"example.com/server/repository"
+)
+
+func TestSQLiteMigrator(t *testing.T) {
+	cfg := repository.SQLiteConfig{Path: filepath.Join(t.TempDir(), "modart.db")}
+	migrator, err := repository.NewSQLiteMigrator(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer migrator.Close()
+
+	statuses := mustStatus(t, migrator)
+	if len(statuses) == 0 {
+		t.Fatal("no migrations")
+	}
+	for _, status := range statuses {
+		if status.Applied {
+			t.Errorf("migration %d applied to a new database", status.Version)
+		}
+	}
+	if err := migrator.Check(); !errors.Is(err, repository.ErrSchemaOutdated) {
+		t.Errorf("Check before Up: got %v, want ErrSchemaOutdated", err)
+	}
+
+	applied, err := migrator.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(statuses) {
+		t.Errorf("Up applied %d migrations, want %d", len(applied), len(statuses))
+	}
+	for i, status := range mustStatus(t, migrator) {
+		if !status.Applied || status.AppliedAt == 0 || status.Version != i+1 {
+			t.Errorf("after Up: %+v", status)
+		}
+	}
+	if again, err := migrator.Up(); err != nil || len(again) != 0 {
+		t.Errorf("second Up: applied %d, %v", len(again), err)
+	}
+	if err := migrator.Check(); err != nil {
+		t.Errorf("Check after Up: %v", err)
+	}
+
+	latest, err := migrator.Down()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest == nil || latest.Version != len(statuses) {
+		t.Fatalf("Down reverted %+v, want migration %d", latest, len(statuses))
+	}
+	after := mustStatus(t, migrator)
+	if after[len(after)-1].Applied || !after[len(after)-2].Applied {
+		t.Errorf("after Down: %+v", after)
+	}
+	if _, err := repository.NewSQLiteDB(cfg); !errors.Is(err, repository.ErrSchemaOutdated) {
+		t.Errorf("opening an outdated database: got %v, want ErrSchemaOutdated", err)
+	}
+
+	// Every down file runs, and the schema can be rebuilt after them all.
+	for version := len(statuses) - 1; version > 0; version-- {
+		reverted, err := migrator.Down()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reverted.Version != version {
+			t.Fatalf("Down reverted %d, want %d", reverted.Version, version)
+		}
+	}
+	if none, err := migrator.Down(); none != nil || err != nil {
+		t.Errorf("Down with nothing applied: %+v, %v", none, err)
+	}
+	if applied, err := migrator.Up(); err != nil || len(applied) != len(statuses) {
+		t.Errorf("Up after reverting everything: applied %d, %v", len(applied), err)
+	}
+	db, err := repository.NewSQLiteDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ReadAuthors(app.PageRequest{Limit: 10}); err != nil {
+		t.Errorf("reading the rebuilt schema: %v", err)
+	}
+}
+
+// TestPostgresMigrator reverts and reapplies the latest migration on the
+// server at DATABASE_HOST, and is skipped without one.
+func TestPostgresMigrator(t *testing.T) {
+	cfg := repository.PostgresConfig{
+		Host:     os.Getenv("DATABASE_HOST"),
+		Port:     os.Getenv("DATABASE_PORT"),
+		User:     os.Getenv("POSTGRES_USER"),
+		DBName:   os.Getenv("POSTGRES_DB"),
+		Password: os.Getenv("POSTGRES_PASSWORD"),
+	}
+	if cfg.Host == "" {
+		t.Skip("DATABASE_HOST is not set")
+	}
+	if cfg.Port == "" {
+		cfg.Port = "5432"
+	}
+	migrator, err := repository.NewPostgresMigrator(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer migrator.Close()
+	if _, err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+	statuses := mustStatus(t, migrator)
+	latest, err := migrator.Down()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest == nil || latest.Version != len(statuses) {
+		t.Fatalf("Down reverted %+v, want migration %d", latest, len(statuses))
+	}
+	if applied, err := migrator.Up(); err != nil || len(applied) != 1 {
+		t.Fatalf("Up after Down: applied %d, %v", len(applied), err)
+	}
+	for _, status := range mustStatus(t, migrator) {
+		if !status.Applied {
+			t.Errorf("migration %d not applied", status.Version)
+		}
+	}
+}
+
+func mustStatus(t *testing.T, migrator *repository.Migrator) []repository.MigrationStatus {
+	t.Helper()
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return statuses
+}
@@ -0,0 +1,3 @@
+-- Nothing to revert: this migration adopts the tables gorm's AutoMigrate
+-- created, with whatever data they hold, so dropping them would destroy the
+-- database it was run against.
@@ -0,0 +1,96 @@
+-- The schema gorm's AutoMigrate created before migrations existed. Tables it
+-- made are adopted as they are: each table is created with its key only and
+-- every other column is added if it is missing, since columns were added to
+-- the models over time.
+
+CREATE TABLE IF NOT EXISTS authors (id varchar(255) PRIMARY KEY);
+ALTER TABLE authors
+	ADD COLUMN IF NOT EXISTS first_name varchar(255),
+	ADD COLUMN IF NOT EXISTS last_name varchar(255),
+	ADD COLUMN IF NOT EXISTS email varchar(255),
+	ADD COLUMN IF NOT EXISTS role varchar(255),
+	ADD COLUMN IF NOT EXISTS email_verified boolean,
+	ADD COLUMN IF NOT EXISTS totp_enabled boolean,
+	ADD COLUMN IF NOT EXISTS password varchar(255),
+	ADD COLUMN IF NOT EXISTS totp_secret varchar(255),
+	ADD COLUMN IF NOT EXISTS totp_recovery_codes varchar(255),
+	ADD COLUMN IF NOT EXISTS totp_last_step bigint,
+	ADD COLUMN IF NOT EXISTS identity_issuer varchar(255),
+	ADD COLUMN IF NOT EXISTS identity_subject varchar(255),
+	ADD COLUMN IF NOT EXISTS version bigint;
+
+CREATE TABLE IF NOT EXISTS articles (id varchar(255) PRIMARY KEY);
+ALTER TABLE articles
+	ADD COLUMN IF NOT EXISTS author_id varchar(255),
+	ADD COLUMN IF NOT EXISTS title varchar(255),
+	ADD COLUMN IF NOT EXISTS body varchar(255),
+	ADD COLUMN IF NOT EXISTS author varchar(255),
+	ADD COLUMN IF NOT EXISTS rate integer,
+	ADD COLUMN IF NOT EXISTS create_at bigint,
+	ADD COLUMN IF NOT EXISTS status varchar(255),
+	ADD COLUMN IF NOT EXISTS published_at bigint,
+	ADD COLUMN IF NOT EXISTS publish_at bigint,
+	ADD COLUMN IF NOT EXISTS version bigint;
+
+CREATE TABLE IF NOT EXISTS revisions (
+	article_id varchar(255),
+	number integer,
+	title varchar(255),
+	body varchar(255),
+	author varchar(255),
+	rate integer,
+	editor_id varchar(255),
+	create_at bigint,
+	PRIMARY KEY (article_id, number)
+);
+
+CREATE TABLE IF NOT EXISTS sessions (id varchar(255) PRIMARY KEY);
+ALTER TABLE sessions
+	ADD COLUMN IF NOT EXISTS author_id varchar(255),
+	ADD COLUMN IF NOT EXISTS token_hash varchar(255),
+	ADD COLUMN IF NOT EXISTS user_agent varchar(255),
+	ADD COLUMN IF NOT EXISTS ip varchar(255),
+	ADD COLUMN IF NOT EXISTS create_at bigint,
+	ADD COLUMN IF NOT EXISTS last_used_at bigint,
+	ADD COLUMN IF NOT EXISTS expires_at bigint,
+	ADD COLUMN IF NOT EXISTS revoked_at bigint;
+
+CREATE TABLE IF NOT EXISTS api_keys (id varchar(255) PRIMARY KEY);
+ALTER TABLE api_keys
+	ADD COLUMN IF NOT EXISTS author_id varchar(255),
+	ADD COLUMN IF NOT EXISTS name varchar(255),
+	ADD COLUMN IF NOT EXISTS scopes varchar(255),
+	ADD COLUMN IF NOT EXISTS key_hash varchar(255),
+	ADD COLUMN IF NOT EXISTS create_at bigint,
+	ADD COLUMN IF NOT EXISTS last_used_at bigint,
+	ADD COLUMN IF NOT EXISTS revoked_at bigint;
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+	key varchar(255) PRIMARY KEY,
+	failures integer,
+	last_failure bigint,
+	locked_until bigint
+);
+
+CREATE TABLE IF NOT EXISTS audit_entries (
+	id varchar(255) PRIMARY KEY,
+	event varchar(255),
+	subject varchar(255),
+	ip varchar(255),
+	reason varchar(255),
+	create_at bigint
+);
+
+-- A weighted tsvector per article, title above body, in a table of its own
+-- so the index is only touched through app.SearchIndex.
+CREATE TABLE IF NOT EXISTS article_search (
+	id text PRIMARY KEY,
+	document tsvector NOT NULL
+);
+CREATE INDEX IF NOT EXISTS article_search_document_idx ON article_search USING GIN (document);
+
+-- Articles from before statuses existed were public, so they are published.
+UPDATE articles SET status = 'published', published_at = create_at WHERE status IS NULL OR status = '';
+UPDATE articles SET publish_at = 0 WHERE publish_at IS NULL;
+UPDATE authors SET version = 0 WHERE version IS NULL;
+UPDATE articles SET version = 0 WHERE version IS NULL;
@@ -0,0 +1,4 @@
+-- Fails rather than truncates when a value no longer fits.
+ALTER TABLE authors ALTER COLUMN totp_recovery_codes TYPE varchar(255);
+ALTER TABLE revisions ALTER COLUMN body TYPE varchar(255);
+ALTER TABLE articles ALTER COLUMN body TYPE varchar(255);
@@ -0,0 +1,6 @@
+-- varchar(255), gorm's default for strings, cuts article bodies well short
+-- of the 100000 bytes validation allows and cannot hold the hashed recovery
+-- codes of an author with two-factor login.
+ALTER TABLE articles ALTER COLUMN body TYPE text;
+ALTER TABLE revisions ALTER COLUMN body TYPE text;
+ALTER TABLE authors ALTER COLUMN totp_recovery_codes TYPE text;
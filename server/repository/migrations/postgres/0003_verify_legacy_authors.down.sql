@@ -0,0 +1,2 @@
+-- Nothing to revert: which authors 0003 verified is not recorded, and they
+-- stay verified.
@@ -0,0 +1,4 @@
+-- Authors from before email verification existed signed up without it, so
+-- they count as verified rather than losing the right to publish. Authors
+-- created since always have the column set.
+UPDATE authors SET email_verified = true WHERE email_verified IS NULL;
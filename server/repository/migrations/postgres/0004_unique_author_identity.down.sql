@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS authors_identity_idx;
+DROP INDEX IF EXISTS authors_email_idx;
@@ -0,0 +1,6 @@
+-- Two authors may not share an email address, in any case, or an external
+-- identity; the service checks first, and these settle concurrent signups.
+-- Creating them fails while duplicates exist, which must be merged by hand.
+CREATE UNIQUE INDEX authors_email_idx ON authors (lower(email));
+CREATE UNIQUE INDEX authors_identity_idx ON authors (identity_issuer, identity_subject)
+	WHERE identity_subject <> '';
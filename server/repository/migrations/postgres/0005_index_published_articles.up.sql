@@ -0,0 +1,8 @@
+-- article_search is only written as articles change, so articles published
+-- before it existed were missing from search. Rebuild it from every
+-- published article.
+DELETE FROM article_search;
+INSERT INTO article_search (id, document)
+SELECT id, setweight(to_tsvector('english', coalesce(title, '')), 'A') || setweight(to_tsvector('english', coalesce(body, '')), 'B')
+FROM articles
+WHERE status = 'published';
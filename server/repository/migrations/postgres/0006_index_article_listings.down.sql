@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS articles_status_create_at_idx;
+DROP INDEX IF EXISTS articles_author_create_at_idx;
+DROP INDEX IF EXISTS articles_create_at_idx;
@@ -0,0 +1,5 @@
+-- Article listings page by keyset over (create_at, id), optionally within
+-- one author or status; without these every page sorted the whole table.
+CREATE INDEX IF NOT EXISTS articles_create_at_idx ON articles (create_at, id);
+CREATE INDEX IF NOT EXISTS articles_author_create_at_idx ON articles (author_id, create_at, id);
+CREATE INDEX IF NOT EXISTS articles_status_create_at_idx ON articles (status, create_at, id);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS article_search;
+DROP TABLE IF EXISTS audit_entries;
+DROP TABLE IF EXISTS login_attempts;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS revisions;
+DROP TABLE IF EXISTS articles;
+DROP TABLE IF EXISTS authors;
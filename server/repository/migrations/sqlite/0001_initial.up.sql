@@ -0,0 +1,93 @@
+-- SQLite databases were only ever created from the current models, so
+-- creating the tables when missing is enough to adopt them.
+
+CREATE TABLE IF NOT EXISTS authors (
+	id text PRIMARY KEY,
+	first_name text,
+	last_name text,
+	email text,
+	role text,
+	email_verified boolean,
+	totp_enabled boolean,
+	password text,
+	totp_secret text,
+	totp_recovery_codes text,
+	totp_last_step bigint,
+	identity_issuer text,
+	identity_subject text,
+	version bigint
+);
+
+CREATE TABLE IF NOT EXISTS articles (
+	id text PRIMARY KEY,
+	author_id text,
+	title text,
+	body text,
+	author text,
+	rate integer,
+	create_at bigint,
+	status text,
+	published_at bigint,
+	publish_at bigint,
+	version bigint
+);
+
+CREATE TABLE IF NOT EXISTS revisions (
+	article_id text,
+	number integer,
+	title text,
+	body text,
+	author text,
+	rate integer,
+	editor_id text,
+	create_at bigint,
+	PRIMARY KEY (article_id, number)
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	id text PRIMARY KEY,
+	author_id text,
+	token_hash text,
+	user_agent text,
+	ip text,
+	create_at bigint,
+	last_used_at bigint,
+	expires_at bigint,
+	revoked_at bigint
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+	id text PRIMARY KEY,
+	author_id text,
+	name text,
+	scopes text,
+	key_hash text,
+	create_at bigint,
+	last_used_at bigint,
+	revoked_at bigint
+);
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+	key text PRIMARY KEY,
+	failures integer,
+	last_failure bigint,
+	locked_until bigint
+);
+
+CREATE TABLE IF NOT EXISTS audit_entries (
+	id text PRIMARY KEY,
+	event text,
+	subject text,
+	ip text,
+	reason text,
+	create_at bigint
+);
+
+-- Articles tokenised like the in-memory index: lower-cased letters and
+-- digits, accents kept.
+CREATE VIRTUAL TABLE IF NOT EXISTS article_search USING fts5(
+	id UNINDEXED,
+	title,
+	body,
+	tokenize = 'unicode61 remove_diacritics 0'
+);
@@ -0,0 +1,2 @@
+-- Nothing to revert: the rebuilt index holds what writes would have put in
+-- it.
@@ -0,0 +1,8 @@
+-- article_search is only written as articles change, so articles published
+-- before it existed were missing from search. Rebuild it from every
+-- published article.
+DELETE FROM article_search;
+INSERT INTO article_search (id, title, body)
+SELECT id, coalesce(title, ''), coalesce(body, '')
+FROM articles
+WHERE status = 'published';
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	app "example.com/server/app"
+
+	errs "github.com/pkg/errors"
+)
+
+// The article_search table holds a weighted tsvector per article, title
+// above body; see migrations/postgres.
+
+func (r postgresRepository) IndexArticle(article *app.Article) error {
+	res := r.db.Exec(`INSERT INTO article_search (id, document)
+		VALUES (?, setweight(to_tsvector('english', ?), 'A') || setweight(to_tsvector('english', ?), 'B'))
+		ON CONFLICT (id) DO UPDATE SET document = EXCLUDED.document`,
+		article.Id, article.Title, article.Body)
+	if res.Error != nil {
+		return errs.Wrap(res.Error, "article not indexed")
+	}
+	return nil
+}
+
+func (r postgresRepository) RemoveArticle(id string) error {
+	res := r.db.Exec(`DELETE FROM article_search WHERE id = ?`, id)
+	if res.Error != nil {
+		return errs.Wrap(res.Error, "article not removed from search")
+	}
+	return nil
+}
+
+// searchRow is an article joined with its rank and headline.
+type searchRow struct {
+	app.Article
+	Score   float64
+	Snippet string
+}
+
+func (r postgresRepository) SearchArticles(query string, limit int) ([]*app.SearchHit, error) {
+	var rows []searchRow
+	// The body is HTML-escaped before ts_headline adds the <mark> tags so
+	// snippets match those of the in-memory index.
+	res := r.db.Raw(`SELECT a.*,
+			ts_rank(s.document, q) AS score,
+			ts_headline('english',
+				replace(replace(replace(a.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+				q, 'StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=15') AS snippet
+		FROM article_search s
+		JOIN articles a ON a.id = s.id,
+		plainto_tsquery('english', ?) q
+		WHERE s.document @@ q
+		ORDER BY score DESC, a.create_at DESC
+		LIMIT ?`, query, limit).Scan(&rows)
+	if res.Error != nil {
+		return nil, errs.Wrap(res.Error, "articles not searched")
+	}
+	hits := make([]*app.SearchHit, len(rows))
+	for i, row := range rows {
+		article := row.Article
+		hits[i] = &app.SearchHit{
+			Article: &article,
+			Score:   row.Score,
+			Snippet: row.Snippet,
+		}
+	}
+	return hits, nil
+}
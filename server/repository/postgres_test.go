@@ -0,0 +1,34 @@
+package repository_test
+
+import (
+	"os"
+	"testing"
+
+	app "example.com/server/app"
+	"example.com/server/repository"
+	"example.com/server/repository/repotest"
+)
+
+// TestPostgresqlDB runs against the server at DATABASE_HOST, configured by
+// the same variables as the server, and is skipped without one.
+func TestPostgresqlDB(t *testing.T) {
+	cfg := repository.PostgresConfig{
+		Host:     os.Getenv("DATABASE_HOST"),
+		Port:     os.Getenv("DATABASE_PORT"),
+		User:     os.Getenv("POSTGRES_USER"),
+		DBName:   os.Getenv("POSTGRES_DB"),
+		Password: os.Getenv("POSTGRES_PASSWORD"),
+	}
+	if cfg.Host == "" {
+		t.Skip("DATABASE_HOST is not set")
+	}
+	if cfg.Port == "" {
+		cfg.Port = "5432"
+	}
+	migrateUp(t)(repository.NewPostgresMigrator(cfg))
+	db, err := repository.NewPostgresqlDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repotest.Run(t, func(t *testing.T) app.AppRepository { return db })
+}
@@ -0,0 +1,395 @@
+// Package repotest is the contract every app.AppRepository implementation
+// must meet, as a suite each backend runs from its own test:
+//
+//	func TestMemoryDB(t *testing.T) {
+//		repotest.Run(t, func(t *testing.T) app.AppRepository {
+//			return repository.NewMemoryDB()
+//		})
+//	}
+//
+// For Postgres, point a repository.PostgresConfig at a throwaway server such
+// as `docker run -p 5432:5432 -e POSTGRES_PASSWORD=... postgres`. For
+// DynamoDB, set the Endpoint of a repository.DynamoDBConfig to DynamoDB Local,
+// such as `docker run -p 8000:8000 amazon/dynamodb-local`, with the tables and
+// indexes the backend expects created. The repository package's own tests do
+// both when DATABASE_HOST or DYNAMODB_ENDPOINT is set: they migrate the
+// Postgres database first and create DynamoDB tables for the run. CI starts
+// both servers, so the suite runs against every backend there.
+//
+// The suite only creates items under fresh IDs and never assumes the store
+// starts empty, so backends can share one database across runs.
+package repotest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	app "example.com/server/app"
+
+	"github.com/google/uuid"
+)
+
+// Factory returns the repository under test.
+type Factory func(t *testing.T) app.AppRepository
+
+// Run checks repo against the AppRepository contract.
+func Run(t *testing.T, factory Factory) {
+	t.Run("Authors", func(t *testing.T) { testAuthors(t, factory(t)) })
+	t.Run("AuthorEmails", func(t *testing.T) { testAuthorEmails(t, factory(t)) })
+	t.Run("AuthorPages", func(t *testing.T) { testAuthorPages(t, factory(t)) })
+	t.Run("Articles", func(t *testing.T) { testArticles(t, factory(t)) })
+	t.Run("ArticlePages", func(t *testing.T) { testArticlePages(t, factory(t)) })
+	t.Run("ArticleQueries", func(t *testing.T) { testArticleQueries(t, factory(t)) })
+}
+
+func testAuthors(t *testing.T, repo app.AppRepository) {
+	email := uuid.New().String() + "@example.com"
+	author := &app.Author{
+		Id:         "ignored",
+		FirstName:  "Ada",
+		LastName:   "Lovelace",
+		Email:      email,
+		Role:       app.RoleAuthor,
+		Password:   "stored as given",
+		TOTPSecret: "secret",
+	}
+	created, err := repo.CreateAuthor(author)
+	mustNot(t, err, "CreateAuthor")
+	t.Cleanup(func() { repo.DeleteAuthor(created.Id) })
+	if created.Id == "" || created.Id == "ignored" {
+		t.Errorf("CreateAuthor: Id = %q, want a generated ID", created.Id)
+	}
+	if created.Version != 1 {
+		t.Errorf("CreateAuthor: Version = %d, want 1", created.Version)
+	}
+	other, err := repo.CreateAuthor(&app.Author{Email: uuid.New().String() + "@example.com"})
+	mustNot(t, err, "CreateAuthor")
+	t.Cleanup(func() { repo.DeleteAuthor(other.Id) })
+	if other.Id == created.Id {
+		t.Errorf("CreateAuthor: two authors got ID %q", created.Id)
+	}
+
+	read, err := repo.ReadAuthor(created.Id)
+	mustNot(t, err, "ReadAuthor")
+	// Hashing is the service's job; repositories store what they are given.
+	if read.FirstName != "Ada" || read.LastName != "Lovelace" || read.Email != email ||
+		read.Role != app.RoleAuthor || read.Password != "stored as given" || read.TOTPSecret != "secret" {
+		t.Errorf("ReadAuthor = %+v, want the fields written", read)
+	}
+	byEmail, err := repo.ReadAuthorByEmail(email)
+	mustNot(t, err, "ReadAuthorByEmail")
+	if byEmail.Id != created.Id {
+		t.Errorf("ReadAuthorByEmail: Id = %q, want %q", byEmail.Id, created.Id)
+	}
+	_, err = repo.ReadAuthor(uuid.New().String())
+	wantErr(t, err, app.ErrNotFound, "ReadAuthor of a missing author")
+	_, err = repo.ReadAuthorByEmail(uuid.New().String() + "@example.com")
+	wantErr(t, err, app.ErrNotFound, "ReadAuthorByEmail of an unknown email")
+
+	// Updates replace the whole record, zero values included.
+	update := *read
+	update.FirstName = ""
+	update.EmailVerified = true
+	updated, err := repo.UpdateAuthor(&update)
+	mustNot(t, err, "UpdateAuthor")
+	if updated.Version != 2 {
+		t.Errorf("UpdateAuthor: Version = %d, want 2", updated.Version)
+	}
+	read, err = repo.ReadAuthor(created.Id)
+	mustNot(t, err, "ReadAuthor")
+	if read.FirstName != "" || !read.EmailVerified || read.LastName != "Lovelace" || read.Version != 2 {
+		t.Errorf("ReadAuthor after update = %+v", read)
+	}
+	_, err = repo.UpdateAuthor(&update)
+	wantErr(t, err, app.ErrVersionMismatch, "UpdateAuthor at a stale version")
+	_, err = repo.UpdateAuthor(&app.Author{Id: uuid.New().String(), Version: 1})
+	wantErr(t, err, app.ErrNotFound, "UpdateAuthor of a missing author")
+
+	mustNot(t, repo.DeleteAuthor(created.Id), "DeleteAuthor")
+	_, err = repo.ReadAuthor(created.Id)
+	wantErr(t, err, app.ErrNotFound, "ReadAuthor after delete")
+	wantErr(t, repo.DeleteAuthor(created.Id), app.ErrNotFound, "DeleteAuthor of a deleted author")
+}
+
+// testAuthorEmails checks that an email address, in any case, belongs to one
+// author at a time.
+func testAuthorEmails(t *testing.T, repo app.AppRepository) {
+	create := func(email string) (*app.Author, error) {
+		author, err := repo.CreateAuthor(&app.Author{Email: email})
+		if err == nil {
+			t.Cleanup(func() { repo.DeleteAuthor(author.Id) })
+		}
+		return author, err
+	}
+	local := uuid.New().String()
+	email := local + "@example.com"
+	created, err := create(email)
+	mustNot(t, err, "CreateAuthor")
+
+	byEmail, err := repo.ReadAuthorByEmail(strings.ToUpper(local) + "@Example.COM")
+	mustNot(t, err, "ReadAuthorByEmail in another case")
+	if byEmail.Id != created.Id {
+		t.Errorf("ReadAuthorByEmail in another case: Id = %q, want %q", byEmail.Id, created.Id)
+	}
+	_, err = create(email)
+	wantErr(t, err, app.ErrConflict, "CreateAuthor with a taken email")
+	_, err = create(strings.ToUpper(email))
+	wantErr(t, err, app.ErrConflict, "CreateAuthor with a taken email in another case")
+
+	other, err := create(uuid.New().String() + "@example.com")
+	mustNot(t, err, "CreateAuthor")
+	taking := *other
+	taking.Email = strings.ToUpper(email)
+	_, err = repo.UpdateAuthor(&taking)
+	wantErr(t, err, app.ErrConflict, "UpdateAuthor to a taken email")
+
+	// Moving to another address frees the old one, and so does deleting.
+	moved := *created
+	moved.Email = uuid.New().String() + "@example.com"
+	_, err = repo.UpdateAuthor(&moved)
+	mustNot(t, err, "UpdateAuthor to a new email")
+	_, err = repo.ReadAuthorByEmail(email)
+	wantErr(t, err, app.ErrNotFound, "ReadAuthorByEmail of a former email")
+	reused, err := create(email)
+	mustNot(t, err, "CreateAuthor with a freed email")
+	mustNot(t, repo.DeleteAuthor(reused.Id), "DeleteAuthor")
+	_, err = create(email)
+	mustNot(t, err, "CreateAuthor with the email of a deleted author")
+}
+
+func testAuthorPages(t *testing.T, repo app.AppRepository) {
+	want := map[string]bool{}
+	for i := 0; i < 5; i++ {
+		author, err := repo.CreateAuthor(&app.Author{Email: uuid.New().String() + "@example.com"})
+		mustNot(t, err, "CreateAuthor")
+		t.Cleanup(func() { repo.DeleteAuthor(author.Id) })
+		want[author.Id] = true
+	}
+	// Order is up to the backend, but every author appears exactly once.
+	seen := map[string]bool{}
+	page := app.PageRequest{Limit: 2}
+	for pages := 0; ; pages++ {
+		if pages > 10000 {
+			t.Fatal("ReadAuthors: cursor never ran out")
+		}
+		result, err := repo.ReadAuthors(page)
+		mustNot(t, err, "ReadAuthors")
+		if len(result.Authors) > page.Limit {
+			t.Fatalf("ReadAuthors: %d authors, more than limit %d", len(result.Authors), page.Limit)
+		}
+		for _, author := range result.Authors {
+			if seen[author.Id] {
+				t.Errorf("ReadAuthors: author %s on two pages", author.Id)
+			}
+			seen[author.Id] = true
+		}
+		if result.NextCursor == "" {
+			break
+		}
+		page.Cursor = result.NextCursor
+	}
+	for id := range want {
+		if !seen[id] {
+			t.Errorf("ReadAuthors: author %s on no page", id)
+		}
+	}
+	_, err := repo.ReadAuthors(app.PageRequest{Limit: 2, Cursor: "not a cursor"})
+	wantErr(t, err, app.ErrInvalid, "ReadAuthors with a malformed cursor")
+}
+
+func testArticles(t *testing.T, repo app.AppRepository) {
+	authorID := uuid.New().String()
+	article := &app.Article{
+		Id:       "ignored",
+		AuthorID: authorID,
+		Title:    "Title",
+		Body:     "Body",
+		Author:   "Byline",
+		Rate:     4,
+		CreateAt: 1000,
+		Status:   app.StatusDraft,
+	}
+	created, err := repo.CreateArticle(article)
+	mustNot(t, err, "CreateArticle")
+	t.Cleanup(func() { repo.DeleteArticle(created.Id) })
+	if created.Id == "" || created.Id == "ignored" {
+		t.Errorf("CreateArticle: Id = %q, want a generated ID", created.Id)
+	}
+	if created.Version != 1 {
+		t.Errorf("CreateArticle: Version = %d, want 1", created.Version)
+	}
+	// A second article by the same author must be untouched by updates to
+	// the first.
+	sibling, err := repo.CreateArticle(&app.Article{AuthorID: authorID, Title: "Sibling", Body: "Body", Rate: 2, CreateAt: 1001, Status: app.StatusDraft})
+	mustNot(t, err, "CreateArticle")
+	t.Cleanup(func() { repo.DeleteArticle(sibling.Id) })
+	if sibling.Id == created.Id {
+		t.Errorf("CreateArticle: two articles got ID %q", created.Id)
+	}
+
+	read, err := repo.ReadArticle(created.Id)
+	mustNot(t, err, "ReadArticle")
+	if read.AuthorID != authorID || read.Title != "Title" || read.Body != "Body" || read.Author != "Byline" ||
+		read.Rate != 4 || read.CreateAt != 1000 || read.Status != app.StatusDraft {
+		t.Errorf("ReadArticle = %+v, want the fields written", read)
+	}
+	_, err = repo.ReadArticle(uuid.New().String())
+	wantErr(t, err, app.ErrNotFound, "ReadArticle of a missing article")
+
+	update := *read
+	update.Title = "Edited"
+	update.Rate = 0
+	updated, err := repo.UpdateArticle(&update)
+	mustNot(t, err, "UpdateArticle")
+	if updated.Version != 2 || updated.Title != "Edited" {
+		t.Errorf("UpdateArticle = %+v, want the update at version 2", updated)
+	}
+	read, err = repo.ReadArticle(created.Id)
+	mustNot(t, err, "ReadArticle")
+	if read.Title != "Edited" || read.Rate != 0 || read.Body != "Body" || read.Version != 2 {
+		t.Errorf("ReadArticle after update = %+v", read)
+	}
+	untouched, err := repo.ReadArticle(sibling.Id)
+	mustNot(t, err, "ReadArticle")
+	if untouched.Title != "Sibling" || untouched.Rate != 2 || untouched.Version != 1 {
+		t.Errorf("UpdateArticle changed another article of the author: %+v", untouched)
+	}
+	_, err = repo.UpdateArticle(&update)
+	wantErr(t, err, app.ErrVersionMismatch, "UpdateArticle at a stale version")
+	_, err = repo.UpdateArticle(&app.Article{Id: uuid.New().String(), AuthorID: authorID, Version: 1})
+	wantErr(t, err, app.ErrNotFound, "UpdateArticle of a missing article")
+
+	mustNot(t, repo.DeleteArticle(created.Id), "DeleteArticle")
+	_, err = repo.ReadArticle(created.Id)
+	wantErr(t, err, app.ErrNotFound, "ReadArticle after delete")
+	wantErr(t, repo.DeleteArticle(created.Id), app.ErrNotFound, "DeleteArticle of a deleted article")
+}
+
+// createArticles adds n articles by a new author, the i-th created at 1000+i
+// with rate i%6 and status published.
+func createArticles(t *testing.T, repo app.AppRepository, n int) (string, []*app.Article) {
+	authorID := uuid.New().String()
+	var articles []*app.Article
+	for i := 0; i < n; i++ {
+		article, err := repo.CreateArticle(&app.Article{
+			AuthorID: authorID,
+			Title:    fmt.Sprintf("Article %d", i),
+			Body:     "Body",
+			Rate:     i % 6,
+			CreateAt: int64(1000 + i),
+			Status:   app.StatusPublished,
+		})
+		mustNot(t, err, "CreateArticle")
+		t.Cleanup(func() { repo.DeleteArticle(article.Id) })
+		articles = append(articles, article)
+	}
+	return authorID, articles
+}
+
+// readAll pages through query with limit, failing on a repeated article.
+func readAll(t *testing.T, repo app.AppRepository, query app.ArticleQuery, limit int) []*app.Article {
+	t.Helper()
+	var all []*app.Article
+	seen := map[string]bool{}
+	page := app.PageRequest{Limit: limit}
+	for pages := 0; ; pages++ {
+		if pages > 10000 {
+			t.Fatal("ReadArticles: cursor never ran out")
+		}
+		result, err := repo.ReadArticles(query, page)
+		mustNot(t, err, "ReadArticles")
+		if len(result.Articles) > limit {
+			t.Fatalf("ReadArticles: %d articles, more than limit %d", len(result.Articles), limit)
+		}
+		for _, article := range result.Articles {
+			if seen[article.Id] {
+				t.Errorf("ReadArticles: article %s on two pages", article.Id)
+			}
+			seen[article.Id] = true
+			all = append(all, article)
+		}
+		if result.NextCursor == "" {
+			return all
+		}
+		page.Cursor = result.NextCursor
+	}
+}
+
+func testArticlePages(t *testing.T, repo app.AppRepository) {
+	authorID, articles := createArticles(t, repo, 5)
+
+	// Newest first by default.
+	got := readAll(t, repo, app.ArticleQuery{AuthorID: authorID}, 2)
+	wantOrder(t, "newest first", got, articles[4], articles[3], articles[2], articles[1], articles[0])
+
+	got = readAll(t, repo, app.ArticleQuery{AuthorID: authorID, Sort: []app.SortKey{{Field: app.SortCreatedAt}}}, 2)
+	wantOrder(t, "oldest first", got, articles[0], articles[1], articles[2], articles[3], articles[4])
+
+	got = readAll(t, repo, app.ArticleQuery{AuthorID: authorID, Sort: []app.SortKey{{Field: app.SortRate, Desc: true}}}, 3)
+	wantOrder(t, "highest rate first", got, articles[4], articles[3], articles[2], articles[1], articles[0])
+
+	// A listing without filters, as used to rebuild the search index,
+	// reaches every article.
+	seen := map[string]bool{}
+	for _, article := range readAll(t, repo, app.ArticleQuery{}, 50) {
+		seen[article.Id] = true
+	}
+	for _, article := range articles {
+		if !seen[article.Id] {
+			t.Errorf("ReadArticles without filters: article %s on no page", article.Id)
+		}
+	}
+
+	_, err := repo.ReadArticles(app.ArticleQuery{AuthorID: authorID}, app.PageRequest{Limit: 2, Cursor: "not a cursor"})
+	wantErr(t, err, app.ErrInvalid, "ReadArticles with a malformed cursor")
+}
+
+func testArticleQueries(t *testing.T, repo app.AppRepository) {
+	authorID, articles := createArticles(t, repo, 5)
+	draft := *articles[2]
+	draft.Status = app.StatusDraft
+	_, err := repo.UpdateArticle(&draft)
+	mustNot(t, err, "UpdateArticle")
+
+	got := readAll(t, repo, app.ArticleQuery{AuthorID: authorID, Statuses: []app.ArticleStatus{app.StatusPublished}}, 10)
+	wantOrder(t, "published only", got, articles[4], articles[3], articles[1], articles[0])
+
+	got = readAll(t, repo, app.ArticleQuery{AuthorID: authorID, Statuses: []app.ArticleStatus{app.StatusDraft, app.StatusInReview}}, 10)
+	wantOrder(t, "drafts only", got, articles[2])
+
+	got = readAll(t, repo, app.ArticleQuery{AuthorID: authorID, MinRate: 3}, 10)
+	wantOrder(t, "min_rate 3", got, articles[4], articles[3])
+
+	got = readAll(t, repo, app.ArticleQuery{AuthorID: authorID, Since: 1001, Until: 1003}, 10)
+	wantOrder(t, "created in [1001, 1003)", got, articles[2], articles[1])
+}
+
+func wantOrder(t *testing.T, name string, got []*app.Article, want ...*app.Article) {
+	t.Helper()
+	ids := func(articles []*app.Article) []string {
+		ids := make([]string, len(articles))
+		for i, article := range articles {
+			ids[i] = article.Id
+		}
+		return ids
+	}
+	if fmt.Sprint(ids(got)) != fmt.Sprint(ids(want)) {
+		t.Errorf("ReadArticles %s = %v, want %v", name, ids(got), ids(want))
+	}
+}
+
+func mustNot(t *testing.T, err error, op string) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: %v", op, err)
+	}
+}
+
+func wantErr(t *testing.T, err, kind error, op string) {
+	t.Helper()
+	if !errors.Is(err, kind) {
+		t.Errorf("%s: error %v, want %v", op, err, kind)
+	}
+}
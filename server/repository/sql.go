@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"fmt"
+
+	app "example.com/server/app"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
+)
+
+// sqlRepository keeps everything but search in a SQL database through gorm.
+// The Postgres and SQLite backends share it and add their own full-text
+// search on top.
+type sqlRepository struct {
+	db *gorm.DB
+}
+
+func (r sqlRepository) CreateAuthor(author *app.Author) (*app.Author, error) {
+	author.Id = uuid.New().String()
+	author.Version = 1
+	author.Articles = []app.Article{}
+	res := r.db.Create(author)
+	if res.Error != nil {
+		if isUniqueViolation(res.Error) {
+			return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("author with email :%s or its identity already exists", author.Email))
+		}
+		return nil, errs.Wrap(res.Error, "author not created")
+	}
+	return author, nil
+}
+
+func (r sqlRepository) ReadAuthor(id string) (*app.Author, error) {
+	var author app.Author
+	res := r.db.First(&author, "id = ?", id)
+	if res.RecordNotFound() {
+		return nil, errs.Wrap(app.ErrNotFound, fmt.Sprintf("author with ID :%s not found", id))
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return &author, nil
+}
+
+// ReadAuthorByEmail ignores case, as the unique index on emails does.
+func (r sqlRepository) ReadAuthorByEmail(email string) (*app.Author, error) {
+	var author app.Author
+	res := r.db.First(&author, "lower(email) = lower(?)", email)
+	if res.RecordNotFound() {
+		return nil, errs.Wrap(app.ErrNotFound, fmt.Sprintf("author with email :%s not found", email))
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return &author, nil
+}
+
+func (r sqlRepository) ReadAuthors(page app.PageRequest) (*app.AuthorPage, error) {
+	query := r.db.Order("id").Limit(page.Limit + 1)
+	if page.Cursor != "" {
+		var after idCursor
+		if err := decodeCursor(page.Cursor, &after); err != nil {
+			return nil, err
+		}
+		query = query.Where("id > ?", after.Id)
+	}
+	authors := []*app.Author{}
+	if res := query.Find(&authors); res.Error != nil {
+		return nil, errs.Wrap(res.Error, "authors not read")
+	}
+	result := &app.AuthorPage{Authors: authors}
+	if len(authors) > page.Limit {
+		result.Authors = authors[:page.Limit]
+		cursor, err := encodeCursor(idCursor{Id: authors[page.Limit-1].Id})
+		if err != nil {
+			return nil, err
+		}
+		result.NextCursor = cursor
+	}
+	return result, nil
+}
+
+func (r sqlRepository) UpdateAuthor(author *app.Author) (*app.Author, error) {
+	next := *author
+	next.Version++
+	updated, err := r.updateVersioned(&next, author.Version)
+	if isUniqueViolation(err) {
+		return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("author with email :%s or its identity already exists", author.Email))
+	}
+	if err != nil {
+		return nil, errs.Wrap(err, "author not updated")
+	}
+	if !updated {
+		if _, err := r.ReadAuthor(author.Id); err != nil {
+			return nil, err
+		}
+		return nil, errs.Wrap(app.ErrVersionMismatch, fmt.Sprintf("author with ID: %s is not at version %d", author.Id, author.Version))
+	}
+	return &next, nil
+}
+
+func (r sqlRepository) DeleteAuthor(id string) error {
+	var deletedAuthor app.Author
+	result := r.db.Where("id = ?", id).Delete(&deletedAuthor)
+	if result.Error != nil {
+		return errs.Wrap(result.Error, "author not deleted")
+	}
+	if result.RowsAffected == 0 {
+		return errs.Wrap(app.ErrNotFound, fmt.Sprintf("author with ID :%s not found", id))
+	}
+	return nil
+}
+
+func (r sqlRepository) CreateArticle(article *app.Article) (*app.Article, error) {
+	article.Id = uuid.New().String()
+	article.Version = 1
+	res := r.db.Create(article)
+	if res.Error != nil {
+		if isUniqueViolation(res.Error) {
+			return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("article with ID :%s already exists", article.Id))
+		}
+		return nil, errs.Wrap(res.Error, "article not created")
+	}
+
+	return article, nil
+}
+
+func (r sqlRepository) ReadArticle(id string) (*app.Article, error) {
+	var article app.Article
+
+	res := r.db.First(&article, "id = ?", id)
+
+	if res.RecordNotFound() {
+		return nil, errs.Wrap(app.ErrNotFound, fmt.Sprintf("article with ID :%s not found", id))
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return &article, nil
+}
+
+func (r sqlRepository) ReadArticles(query app.ArticleQuery, page app.PageRequest) (*app.ArticlePage, error) {
+	columns := articleOrder(query.Sort)
+	db := r.db.Order(orderClause(columns)).Limit(page.Limit + 1)
+	if query.AuthorID != "" {
+		db = db.Where("author_id = ?", query.AuthorID)
+	}
+	if len(query.Statuses) > 0 {
+		db = db.Where("status IN (?)", query.Statuses)
+	}
+	if query.MinRate != 0 {
+		db = db.Where("rate >= ?", query.MinRate)
+	}
+	if query.Since != 0 {
+		db = db.Where("create_at >= ?", query.Since)
+	}
+	if query.Until != 0 {
+		db = db.Where("create_at < ?", query.Until)
+	}
+	if page.Cursor != "" {
+		var after articleCursor
+		if err := decodeCursor(page.Cursor, &after); err != nil {
+			return nil, err
+		}
+		if after.Sort != app.SortSpec(query.Sort) {
+			return nil, errs.Wrap(app.ErrInvalidCursor, "cursor is for another sort order")
+		}
+		condition, args := keysetCondition(columns, after.values(columns))
+		db = db.Where(condition, args...)
+	}
+	articles := []*app.Article{}
+	if res := db.Find(&articles); res.Error != nil {
+		return nil, errs.Wrap(res.Error, "articles not read")
+	}
+	result := &app.ArticlePage{Articles: articles}
+	if len(articles) > page.Limit {
+		result.Articles = articles[:page.Limit]
+		cursor, err := encodeCursor(newArticleCursor(query.Sort, articles[page.Limit-1]))
+		if err != nil {
+			return nil, err
+		}
+		result.NextCursor = cursor
+	}
+	return result, nil
+}
+
+func (r sqlRepository) UpdateArticle(article *app.Article) (*app.Article, error) {
+	next := *article
+	next.Version++
+	updated, err := r.updateVersioned(&next, article.Version)
+	if err != nil {
+		return nil, errs.Wrap(err, "article not updated")
+	}
+	if !updated {
+		if _, err := r.ReadArticle(article.Id); err != nil {
+			return nil, err
+		}
+		return nil, errs.Wrap(app.ErrVersionMismatch, fmt.Sprintf("article with ID: %s is not at version %d", article.Id, article.Version))
+	}
+	return &next, nil
+}
+
+func (r sqlRepository) DeleteArticle(id string) error {
+	var deletedArticle app.Article
+	result := r.db.Where("id = ?", id).Delete(&deletedArticle)
+	if result.Error != nil {
+		return errs.Wrap(result.Error, "article not deleted")
+	}
+	if result.RowsAffected == 0 {
+		return errs.Wrap(app.ErrNotFound, fmt.Sprintf("article with ID: %s not found", id))
+	}
+	return nil
+}
+
+// updateVersioned writes every column of value, which already carries its
+// next version, over the row with its primary key if that row is still at
+// version. It reports whether the row was written.
+func (r sqlRepository) updateVersioned(value interface{}, version int64) (bool, error) {
+	scope := r.db.NewScope(value)
+	columns := map[string]interface{}{}
+	for _, field := range scope.Fields() {
+		if field.IsNormal && !field.IsPrimaryKey {
+			columns[field.DBName] = field.Field.Interface()
+		}
+	}
+	// Table rather than Model, which would drop columns equal to value's own.
+	res := r.db.Table(scope.TableName()).
+		Where(scope.PrimaryKey()+" = ? AND version = ?", scope.PrimaryKeyValue(), version).
+		UpdateColumns(columns)
+	return res.RowsAffected > 0, res.Error
+}
+
+// isUniqueViolation reports whether err is the database rejecting a
+// duplicate key.
+func isUniqueViolation(err error) bool {
+	return isPostgresUniqueViolation(err) || isSQLiteUniqueViolation(err)
+}
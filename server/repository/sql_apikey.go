@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"fmt"
+
+	app "example.com/server/app"
+
+	errs "github.com/pkg/errors"
+)
+
+func (r sqlRepository) CreateAPIKey(key *app.APIKey) (*app.APIKey, error) {
+	res := r.db.Create(key)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return key, nil
+}
+
+func (r sqlRepository) ReadAPIKey(id string) (*app.APIKey, error) {
+	var key app.APIKey
+	res := r.db.First(&key, "id = ?", id)
+	if res.RowsAffected == 0 {
+		return nil, errs.Wrap(app.ErrNotFound, fmt.Sprintf("api key with ID :%s not found", id))
+	}
+	return &key, nil
+}
+
+func (r sqlRepository) ReadAPIKeys(authorID string) ([]*app.APIKey, error) {
+	var keys []*app.APIKey
+	res := r.db.Where("author_id = ?", authorID).Order("create_at desc").Find(&keys)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return keys, nil
+}
+
+func (r sqlRepository) UpdateAPIKey(key *app.APIKey) (*app.APIKey, error) {
+	res := r.db.Save(key)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return key, nil
+}
+
+func (r sqlRepository) TouchAPIKey(id string, usedAt int64) error {
+	res := r.db.Model(&app.APIKey{}).Where("id = ? AND revoked_at = 0", id).Update("last_used_at", usedAt)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errs.Wrap(app.ErrNotFound, fmt.Sprintf("unrevoked api key with ID :%s not found", id))
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	app "example.com/server/app"
+)
+
+func (r sqlRepository) ReadAttempts(key string) (*app.LoginAttempts, error) {
+	var attempts app.LoginAttempts
+	res := r.db.Where("key = ?", key).Find(&attempts)
+	if res.RecordNotFound() {
+		return &app.LoginAttempts{Key: key}, nil
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return &attempts, nil
+}
+
+// RecordFailure is a single upsert, which Postgres and SQLite both apply
+// atomically. Every expression in its SET sees the row as it was before.
+func (r sqlRepository) RecordFailure(key string, now, since int64, threshold int, lockUntil int64) (*app.LoginAttempts, error) {
+	var attempts app.LoginAttempts
+	res := r.db.Raw(`INSERT INTO login_attempts (key, failures, last_failure, locked_until)
+		VALUES (?, 1, ?, CASE WHEN 1 >= ? THEN ? ELSE 0 END)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_attempts.last_failure < ? THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failure = excluded.last_failure,
+			locked_until = CASE
+				WHEN login_attempts.last_failure < ? THEN excluded.locked_until
+				WHEN login_attempts.failures + 1 >= ? AND login_attempts.locked_until <= excluded.last_failure THEN ?
+				ELSE login_attempts.locked_until
+			END
+		RETURNING key, failures, last_failure, locked_until`,
+		key, now, threshold, lockUntil,
+		since,
+		since, threshold, lockUntil,
+	).Scan(&attempts)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return &attempts, nil
+}
+
+func (r sqlRepository) DeleteAttempts(key string) error {
+	return r.db.Where("key = ?", key).Delete(&app.LoginAttempts{}).Error
+}
+
+func (r sqlRepository) CreateAuditEntry(entry *app.AuditEntry) error {
+	return r.db.Create(entry).Error
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"fmt"
+
+	app "example.com/server/app"
+
+	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
+)
+
+func (r sqlRepository) CreateRevision(revision *app.Revision) (*app.Revision, error) {
+	res := r.db.Create(revision)
+	if res.Error != nil {
+		if isUniqueViolation(res.Error) {
+			return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("revision %d of article %s already exists", revision.Number, revision.ArticleID))
+		}
+		return nil, errs.Wrap(res.Error, "revision not created")
+	}
+	return revision, nil
+}
+
+// UpdateArticleWithRevision makes both writes in one transaction.
+func (r sqlRepository) UpdateArticleWithRevision(article *app.Article, revision *app.Revision) (*app.Article, error) {
+	var updated *app.Article
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if updated, err = (sqlRepository{db: tx}).UpdateArticle(article); err != nil {
+			return err
+		}
+		_, err = sqlRepository{db: tx}.CreateRevision(revision)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (r sqlRepository) ReadRevision(articleID string, number int) (*app.Revision, error) {
+	var revision app.Revision
+	res := r.db.First(&revision, "article_id = ? AND number = ?", articleID, number)
+	if res.RecordNotFound() {
+		return nil, errs.Wrap(app.ErrNotFound, fmt.Sprintf("revision %d of article %s not found", number, articleID))
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return &revision, nil
+}
+
+func (r sqlRepository) ReadRevisions(articleID string) ([]*app.Revision, error) {
+	revisions := []*app.Revision{}
+	res := r.db.Where("article_id = ?", articleID).Order("number").Find(&revisions)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return revisions, nil
+}
+
+func (r sqlRepository) DeleteRevisions(articleID string) error {
+	res := r.db.Where("article_id = ?", articleID).Delete(&app.Revision{})
+	if res.Error != nil {
+		return errs.Wrap(res.Error, "revisions not deleted")
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"fmt"
+
+	app "example.com/server/app"
+
+	errs "github.com/pkg/errors"
+)
+
+var editableStatuses = []app.ArticleStatus{app.StatusDraft, app.StatusInReview}
+
+func (r sqlRepository) ReadDueArticles(now int64, limit int) ([]*app.Article, error) {
+	articles := []*app.Article{}
+	res := r.db.
+		Where("status IN (?) AND publish_at > 0 AND publish_at <= ?", editableStatuses, now).
+		Order("publish_at").
+		Limit(limit).
+		Find(&articles)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return articles, nil
+}
+
+// PublishScheduledArticle publishes the article with an UPDATE whose WHERE
+// clause requires it to still be unpublished and scheduled for publishAt.
+// A replica whose UPDATE waited on another's row lock re-checks the clause
+// against the committed row and matches nothing, so each article is
+// published exactly once.
+func (r sqlRepository) PublishScheduledArticle(id string, publishAt, publishedAt int64) (*app.Article, error) {
+	res := r.db.Exec(`UPDATE articles SET status = ?, published_at = ?, publish_at = 0, version = version + 1
+		WHERE id = ? AND publish_at = ? AND status IN (?)`,
+		app.StatusPublished, publishedAt, id, publishAt, editableStatuses)
+	if res.Error != nil {
+		return nil, errs.Wrap(res.Error, "article not published")
+	}
+	if res.RowsAffected == 0 {
+		return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("article with ID: %s no longer scheduled for %d", id, publishAt))
+	}
+	return r.ReadArticle(id)
+}
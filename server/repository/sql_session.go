@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"fmt"
+
+	app "example.com/server/app"
+
+	errs "github.com/pkg/errors"
+)
+
+func (r sqlRepository) CreateSession(session *app.Session) (*app.Session, error) {
+	res := r.db.Create(session)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return session, nil
+}
+
+func (r sqlRepository) ReadSession(id string) (*app.Session, error) {
+	var session app.Session
+	res := r.db.First(&session, "id = ?", id)
+	if res.RowsAffected == 0 {
+		return nil, errs.Wrap(app.ErrNotFound, fmt.Sprintf("session with ID :%s not found", id))
+	}
+	return &session, nil
+}
+
+func (r sqlRepository) ReadSessions(authorID string) ([]*app.Session, error) {
+	var sessions []*app.Session
+	res := r.db.Where("author_id = ?", authorID).Order("create_at desc").Find(&sessions)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return sessions, nil
+}
+
+func (r sqlRepository) UpdateSession(session *app.Session) (*app.Session, error) {
+	res := r.db.Save(session)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return session, nil
+}
+
+func (r sqlRepository) RotateSession(session *app.Session, previousHash string) (*app.Session, error) {
+	res := r.db.Model(&app.Session{}).
+		Where("id = ? AND token_hash = ? AND revoked_at = 0", session.Id, previousHash).
+		Updates(map[string]interface{}{
+			"token_hash":   session.TokenHash,
+			"last_used_at": session.LastUsedAt,
+		})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, errs.Wrap(app.ErrConflict, fmt.Sprintf("session with ID :%s was rotated or revoked", session.Id))
+	}
+	return r.ReadSession(session.Id)
+}
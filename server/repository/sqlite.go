@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"net/url"
+
+	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteRepository adds full-text search with FTS5 to sqlRepository. The
+// driver is pure Go, so binaries using it still build without cgo.
+type sqliteRepository struct {
+	sqlRepository
+}
+
+// SQLiteConfig is the SQLite database file to use.
+type SQLiteConfig struct {
+	Path string `yaml:"path"`
+}
+
+func openSQLite(cfg SQLiteConfig) (*gorm.DB, error) {
+	// Writers wait on each other rather than fail with SQLITE_BUSY, and WAL
+	// lets reads go on while they do.
+	pragmas := url.Values{"_pragma": {
+		"busy_timeout(5000)",
+		"journal_mode(WAL)",
+		"foreign_keys(1)",
+	}}
+	sqlDB, err := sql.Open("sqlite", "file:"+cfg.Path+"?"+pragmas.Encode())
+	if err != nil {
+		return nil, err
+	}
+	// One connection serialises writes, which keeps versioned updates and
+	// scheduled publishing atomic, and lets ":memory:" name a single database.
+	sqlDB.SetMaxOpenConns(1)
+	// gorm's built-in sqlite3 dialect only needs a *sql.DB, so the cgo driver
+	// its dialects/sqlite package imports is not used.
+	db, err := gorm.Open("sqlite3", sqlDB)
+	if err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// newSQLiteDB opens the SQLite database, refusing a schema that is behind.
+func newSQLiteDB(cfg SQLiteConfig) (*gorm.DB, error) {
+	db, err := openSQLite(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSchema(db, "sqlite"); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// NewSQLiteDB opens the SQLite database at cfg.Path, which `modart migrate up`
+// creates.
+func NewSQLiteDB(cfg SQLiteConfig) (Store, error) {
+	db, err := newSQLiteDB(cfg)
+	if err != nil {
+		return nil, errs.Wrap(err, "opening SQLite")
+	}
+	repo := sqliteRepository{
+		sqlRepository{db: db},
+	}
+	return repo, nil
+}
+
+// isSQLiteUniqueViolation reports whether err is SQLite rejecting a duplicate
+// key.
+func isSQLiteUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	code := sqliteErr.Code()
+	return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
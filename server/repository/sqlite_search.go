@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"strings"
+
+	app "example.com/server/app"
+
+	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
+)
+
+// The article_search table is an FTS5 table tokenised like the in-memory
+// index; see migrations/sqlite.
+
+func (r sqliteRepository) IndexArticle(article *app.Article) error {
+	// FTS5 tables have no unique constraint to upsert on.
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`DELETE FROM article_search WHERE id = ?`, article.Id).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`INSERT INTO article_search (id, title, body) VALUES (?, ?, ?)`,
+			article.Id, article.Title, article.Body).Error
+	})
+	if err != nil {
+		return errs.Wrap(err, "article not indexed")
+	}
+	return nil
+}
+
+func (r sqliteRepository) RemoveArticle(id string) error {
+	res := r.db.Exec(`DELETE FROM article_search WHERE id = ?`, id)
+	if res.Error != nil {
+		return errs.Wrap(res.Error, "article not removed from search")
+	}
+	return nil
+}
+
+// SearchArticles ranks with FTS5's BM25, title words weighted as in the
+// in-memory index, and builds snippets the same way it does.
+func (r sqliteRepository) SearchArticles(query string, limit int) ([]*app.SearchHit, error) {
+	hits := []*app.SearchHit{}
+	terms := map[string]bool{}
+	var phrases []string
+	for _, term := range tokenize(query) {
+		if !terms[term] {
+			terms[term] = true
+			// Quoting keeps words like AND or NEAR from being read as
+			// query syntax.
+			phrases = append(phrases, `"`+term+`"`)
+		}
+	}
+	if len(phrases) == 0 {
+		return hits, nil
+	}
+	var rows []searchRow
+	// bm25 is lower for better matches and takes one weight per column.
+	res := r.db.Raw(`SELECT a.*,
+			-bm25(article_search, 0.0, ?, 1.0) AS score
+		FROM article_search
+		JOIN articles a ON a.id = article_search.id
+		WHERE article_search MATCH ?
+		ORDER BY score DESC, a.create_at DESC
+		LIMIT ?`, titleWeight, strings.Join(phrases, " "), limit).Scan(&rows)
+	if res.Error != nil {
+		return nil, errs.Wrap(res.Error, "articles not searched")
+	}
+	for _, row := range rows {
+		article := row.Article
+		hits = append(hits, &app.SearchHit{
+			Article: &article,
+			Score:   row.Score,
+			Snippet: snippet(&article, terms),
+		})
+	}
+	return hits, nil
+}
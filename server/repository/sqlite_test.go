@@ -0,0 +1,78 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	app "example.com/server/app"
+	"example.com/server/repository"
+	"example.com/server/repository/repotest"
+)
+
+func TestSQLiteDB(t *testing.T) {
+	cfg := repository.SQLiteConfig{Path: filepath.Join(t.TempDir(), "modart.db")}
+	migrateUp(t)(repository.NewSQLiteMigrator(cfg))
+	db, err := repository.NewSQLiteDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repotest.Run(t, func(t *testing.T) app.AppRepository { return db })
+}
+
+// migrateUp returns a func that applies every migration with the migrator it
+// is passed, failing t if it cannot.
+func migrateUp(t *testing.T) func(*repository.Migrator, error) {
+	return func(migrator *repository.Migrator, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer migrator.Close()
+		if _, err := migrator.Up(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestSQLiteIndexesPublishedArticles reruns the migration that fills the
+// search index over articles stored without going through it.
+func TestSQLiteIndexesPublishedArticles(t *testing.T) {
+	cfg := repository.SQLiteConfig{Path: filepath.Join(t.TempDir(), "modart.db")}
+	migrateUp(t)(repository.NewSQLiteMigrator(cfg))
+	db, err := repository.NewSQLiteDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	published, err := db.CreateArticle(&app.Article{Title: "Engines", Body: "On the analytical engine.", Status: app.StatusPublished})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.CreateArticle(&app.Article{Title: "Engines", Body: "A draft on engines.", Status: app.StatusDraft}); err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := repository.NewSQLiteMigrator(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Revert down to and including 0003_index_published_articles.
+	for {
+		migration, err := migrator.Down()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if migration.Name == "index_published_articles" {
+			break
+		}
+	}
+	migrator.Close()
+	migrateUp(t)(repository.NewSQLiteMigrator(cfg))
+
+	hits, err := db.SearchArticles("engine", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].Article.Id != published.Id {
+		t.Errorf("got %d hits, want only the published article", len(hits))
+	}
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	app "example.com/server/app"
+)
+
+// Store is everything the services keep in a database. Each backend
+// provides all of it. Postgres and SQLite search articles with their own
+// full-text indexes; the others keep a memory search index, which starts
+// empty and is filled with app.RebuildIndex.
+type Store interface {
+	app.AppRepository
+	app.RevisionRepository
+	app.ScheduleRepository
+	app.SessionRepository
+	app.APIKeyRepository
+	app.AttemptRepository
+	app.SearchIndex
+}